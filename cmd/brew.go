@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+var (
+	brewfileFlags []string
+	brewOutFlag   string
+)
+
+func init() {
+	brewExportCmd.Flags().StringSliceVar(&brewfileFlags, "brewfile", nil, "Additional Brewfile to merge (repeatable)")
+	brewExportCmd.Flags().StringVarP(&brewOutFlag, "output", "o", "Brewfile", "Output path, or - for stdout")
+	brewCmd.AddCommand(brewExportCmd)
+	rootCmd.AddCommand(brewCmd)
+}
+
+var brewCmd = &cobra.Command{
+	Use:   "brew",
+	Short: "Homebrew package set helpers",
+}
+
+var brewExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the effective Homebrew package set to a Brewfile",
+	Long: `Write the packages the homebrew module installs, merged with any
+--brewfile inputs, in Homebrew Bundle format.
+
+Examples:
+  henrik-os brew export                         # ./Brewfile
+  henrik-os brew export -o -                    # stdout
+  henrik-os brew export --brewfile ~/work/Brewfile -o ~/Brewfile`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		module.SetBrewfiles(brewfileFlags)
+		bf, err := module.EffectiveBrewfile()
+		if err != nil {
+			return err
+		}
+
+		if brewOutFlag == "-" {
+			_, err := bf.WriteTo(os.Stdout)
+			return err
+		}
+
+		var buf bytes.Buffer
+		if _, err := bf.WriteTo(&buf); err != nil {
+			return err
+		}
+		if err := module.BackupAndWrite(os.Stderr, module.ExpandHome(brewOutFlag), buf.Bytes(), 0o644); err != nil {
+			return fmt.Errorf("exporting Brewfile: %w", err)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+var doctorFix bool
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair problems that can be fixed automatically")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [modules...]",
+	Short: "Check the environment for common problems",
+	Long: `Run health checks on this machine: Homebrew on PATH and its prefix,
+login shell, Xcode CLI tools, ~/.ssh permissions, GitHub connectivity, free
+disk space and broken config links, plus checks contributed by modules.
+
+Each check passes, warns or fails with a suggested fix. With --fix, checks
+that know how to repair themselves are fixed and run again.
+
+Examples:
+  henrik-os doctor
+  henrik-os doctor --fix
+  henrik-os doctor fish git        # Core checks plus those of fish and git`,
+	ValidArgsFunction: completeModuleIDs,
+	// Failing checks are not usage errors.
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modules, err := modulesByID(args)
+		if err != nil {
+			return err
+		}
+
+		if failed := runDiagnostics(os.Stdout, module.Diagnostics(modules), doctorFix); failed > 0 {
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+// runDiagnostics prints the result of each diagnostic and returns how many
+// failed. With fix, diagnostics that do not pass are repaired when they
+// know how and then run again.
+func runDiagnostics(w io.Writer, diags []module.Diagnostic, fix bool) int {
+	failed := 0
+	for _, d := range diags {
+		r := d.Run()
+		if r.Severity != module.Pass && fix && d.Repair != nil {
+			fmt.Fprintf(w, "  … %s: repairing\n", d.Name)
+			if err := d.Repair(w); err != nil {
+				fmt.Fprintf(w, "    repair failed: %v\n", err)
+			}
+			r = d.Run()
+		}
+
+		fmt.Fprintf(w, "  %s %s: %s\n", severityIcon(r.Severity), d.Name, r.Message)
+		if r.Severity != module.Pass && r.Fix != "" {
+			fmt.Fprintf(w, "      fix: %s\n", r.Fix)
+		}
+		if r.Severity == module.Fail {
+			failed++
+		}
+	}
+	return failed
+}
+
+func severityIcon(s module.Severity) string {
+	switch s {
+	case module.Pass:
+		return "✓"
+	case module.Warn:
+		return "!"
+	}
+	return "✗"
+}
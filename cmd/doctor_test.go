@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+// diagnostic returns a diagnostic that reports broken until repaired, and
+// counts its repairs.
+func diagnostic(name string, severity module.Severity, repairErr error, repairs *int) module.Diagnostic {
+	fixed := false
+	d := module.Diagnostic{
+		Name: name,
+		Run: func() module.Result {
+			if fixed {
+				return module.Result{Severity: module.Pass, Message: "fixed"}
+			}
+			return module.Result{Severity: severity, Message: "broken", Fix: "fix " + name}
+		},
+	}
+	if repairs != nil {
+		d.Repair = func(w io.Writer) error {
+			*repairs++
+			fixed = repairErr == nil
+			return repairErr
+		}
+	}
+	return d
+}
+
+func TestRunDiagnostics(t *testing.T) {
+	tests := []struct {
+		name       string
+		severity   module.Severity
+		repairable bool
+		repairErr  error
+		fix        bool
+		failed     int
+		repairs    int
+		output     string
+	}{
+		{"pass", module.Pass, true, nil, true, 0, 0, "✓ check: broken"},
+		{"warn", module.Warn, false, nil, false, 0, 0, "! check: broken\n      fix: fix check"},
+		{"fail", module.Fail, true, nil, false, 1, 0, "✗ check: broken\n      fix: fix check"},
+		{"fail without repair", module.Fail, false, nil, true, 1, 0, "✗ check: broken"},
+		{"fail repaired", module.Fail, true, nil, true, 0, 1, "… check: repairing\n  ✓ check: fixed"},
+		{"warn repaired", module.Warn, true, nil, true, 0, 1, "✓ check: fixed"},
+		{"repair fails", module.Fail, true, errors.New("no sudo"), true, 1, 1, "repair failed: no sudo\n  ✗ check: broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repairs := 0
+			var counter *int
+			if tt.repairable {
+				counter = &repairs
+			}
+			var out strings.Builder
+			failed := runDiagnostics(&out, []module.Diagnostic{diagnostic("check", tt.severity, tt.repairErr, counter)}, tt.fix)
+			if failed != tt.failed || repairs != tt.repairs {
+				t.Errorf("failed %d, repaired %d times; want %d, %d", failed, repairs, tt.failed, tt.repairs)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output =\n%s\nwant it to contain\n%s", out.String(), tt.output)
+			}
+		})
+	}
+}
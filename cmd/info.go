@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info <module>",
+	Short: "Show what a module installs",
+	Long: `Show a module's description, resolved dependency chain, the files it
+manages, the packages it ensures, the commands it runs and whether it needs
+sudo. Everything is read from the module itself.
+
+Examples:
+  henrik-os info fish
+  henrik-os info claude`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeModuleIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m := module.ByID(args[0])
+		if m == nil {
+			return fmt.Errorf("unknown module: %s", args[0])
+		}
+		w := os.Stdout
+
+		fmt.Fprintf(w, "%s (%s)\n", m.Name(), m.ID())
+		fmt.Fprintf(w, "  %s\n\n", m.Description())
+
+		field := func(label, value string) {
+			fmt.Fprintf(w, "%-14s %s\n", label+":", value)
+		}
+		field("Tags", orDash(module.Tags(m)))
+		field("Platforms", platforms(m))
+		field("Status", moduleStatus(m))
+		field("Dependencies", dependencyChain(m))
+		if s, ok := m.(module.SoftDependent); ok && len(s.SoftDependencies()) > 0 {
+			field("Optional", strings.Join(s.SoftDependencies(), ", "))
+		}
+		if c, ok := m.(module.Conflicter); ok && len(c.Conflicts()) > 0 {
+			field("Conflicts", strings.Join(c.Conflicts(), ", "))
+		}
+		sudo := "no"
+		if len(module.SudoModules([]module.Module{m})) > 0 {
+			sudo = "yes"
+		}
+		field("Needs sudo", sudo)
+
+		if fp, ok := m.(module.FileProvider); ok && len(fp.Files()) > 0 {
+			fmt.Fprintln(w, "\nFiles:")
+			for _, f := range fp.Files() {
+				note := ""
+				if f.Block != "" {
+					note = fmt.Sprintf(" (managed block %q)", f.Block)
+				} else if f.Merge != nil {
+					note = " (merged)"
+				}
+				fmt.Fprintf(w, "  %s → %s%s\n", f.Src, tildePath(f.Dest), note)
+			}
+		}
+
+		if p, ok := m.(module.PackageRequirer); ok && len(p.Packages()) > 0 {
+			pm := module.SystemPackageManager()
+			fmt.Fprintln(w, "\nPackages:")
+			for _, pkg := range p.Packages() {
+				if pm == nil {
+					fmt.Fprintf(w, "  %s\n", pkg)
+				} else if name := module.PackageName(pm.Name(), pkg); name != pkg {
+					fmt.Fprintf(w, "  %s (%s: %s)\n", pkg, pm.Name(), name)
+				} else {
+					fmt.Fprintf(w, "  %s\n", pkg)
+				}
+			}
+		}
+
+		if c, ok := m.(module.Commander); ok && len(c.Commands()) > 0 {
+			fmt.Fprintln(w, "\nCommands:")
+			for _, line := range c.Commands() {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+		}
+		return nil
+	},
+}
+
+func platforms(m module.Module) string {
+	p, ok := m.(module.PlatformModule)
+	if !ok || len(p.Platforms()) == 0 {
+		return "all"
+	}
+	var names []string
+	for _, goos := range p.Platforms() {
+		names = append(names, module.PlatformName(goos))
+	}
+	return strings.Join(names, ", ")
+}
+
+// dependencyChain returns the modules installed for m in order, or the
+// reason it cannot be resolved.
+func dependencyChain(m module.Module) string {
+	if len(m.Dependencies()) == 0 {
+		return "-"
+	}
+	mods, err := module.Resolve([]string{m.ID()})
+	if err != nil {
+		return err.Error()
+	}
+	var ids []string
+	for _, dep := range mods {
+		ids = append(ids, dep.ID())
+	}
+	return strings.Join(ids, " → ")
+}
+
+// tildePath abbreviates the home directory in path to ~.
+func tildePath(path string) string {
+	home := module.HomeDir()
+	if home != "" && strings.HasPrefix(path, home+"/") {
+		return "~" + path[len(home):]
+	}
+	return path
+}
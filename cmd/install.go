@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
@@ -11,10 +12,22 @@ import (
 	"github.com/henrikkvamme/henrik-os/tui"
 )
 
-var allFlag bool
+var (
+	allFlag           bool
+	linkFlag          string
+	skipFailingFlag   bool
+	githubFlag        bool
+	githubSigningFlag bool
+)
 
 func init() {
 	installCmd.Flags().BoolVar(&allFlag, "all", false, "Install all modules (headless)")
+	installCmd.Flags().StringVar(&linkFlag, "link", "", "Symlink configs into this repo checkout instead of copying")
+	installCmd.Flags().BoolVar(&skipFailingFlag, "skip-failing", false, "Skip modules that fail preflight checks instead of aborting")
+	installCmd.Flags().BoolVar(&githubFlag, "github", false, "Upload the SSH key to GitHub (token from GITHUB_TOKEN, GH_TOKEN or gh auth token)")
+	installCmd.Flags().BoolVar(&githubSigningFlag, "github-signing", false, "Also upload the SSH key to GitHub as a signing key")
+	installCmd.Flags().StringSliceVar(&brewfileFlags, "brewfile", nil, "Additional Brewfile for the homebrew module (repeatable)")
+	addSourceFlags(installCmd)
 	rootCmd.AddCommand(installCmd)
 }
 
@@ -27,36 +40,85 @@ Without arguments, launches an interactive TUI for module selection.
 With arguments, installs the specified modules headlessly.
 Use --all to install everything without interaction.
 
+Before anything is changed, modules check their requirements (binaries,
+OS version, disk space, network). Headless runs abort when a check fails
+unless --skip-failing drops those modules and the modules depending on them.
+
+Arguments are selectors: a module ID, a glob (claude*), a tag (tag:editor)
+or all. Prefix a selector with - to exclude it, and combine several with
+commas.
+
 Examples:
   henrik-os install                # Interactive TUI
   henrik-os install --all          # Everything, headless
   henrik-os install fish git       # Specific modules (auto-resolves deps)
-  henrik-os install claude-config  # Just sync Claude Code config`,
-	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		var comps []string
-		for _, m := range module.All() {
-			comps = append(comps, m.ID()+"\t"+m.Description())
-		}
-		return comps, cobra.ShellCompDirectiveNoFileComp
-	},
+  henrik-os install claude-config  # Just sync Claude Code config
+  henrik-os install tag:editor     # Every editor module
+  henrik-os install all,-macos     # Everything except macOS defaults
+  henrik-os install 'claude*'      # claude and claude-config
+  henrik-os install --link ~/dev/henrik-os  # Symlink configs into a checkout
+  henrik-os install homebrew --brewfile ./Brewfile  # Also install a project's Brewfile
+  henrik-os install ssh --github   # Generate a key and add it to your GitHub account
+  henrik-os install --source https://github.com/me/dotfiles.git --source-pin 1a2b3c4`,
+	ValidArgsFunction: completeModuleIDs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if allFlag {
-			// Headless: all modules
-			modules := module.All()
-			return tui.RunHeadless(modules, os.Stdout)
+		if manifestErr != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("not installing with an invalid configuration: %w", manifestErr)
+		}
+		if linkFlag != "" {
+			if err := module.SetLinkRoot(linkFlag); err != nil {
+				return err
+			}
+		}
+		module.SetBrewfiles(brewfileFlags)
+		module.SetGitHubUpload(githubFlag, githubSigningFlag)
+		if err := applySource(); err != nil {
+			return err
 		}
 
-		if len(args) > 0 {
-			// Headless: specific modules
-			modules, err := module.Resolve(args)
+		if allFlag || len(args) > 0 {
+			// Headless: all modules supported on this platform, or the
+			// selected ones with their dependencies
+			var ids []string
+			if allFlag {
+				for _, m := range module.Available() {
+					ids = append(ids, m.ID())
+				}
+			} else {
+				var err error
+				if ids, err = module.Select(args); err != nil {
+					return err
+				}
+			}
+			modules, err := module.Resolve(ids)
 			if err != nil {
 				return err
 			}
+			module.SetInstalling(modules)
+			if modules, err = preflight(modules); err != nil {
+				return err
+			}
+			module.SetInstalling(modules)
+			if err := module.PromptAll(modules); err != nil {
+				return err
+			}
+			defer acquireSudo(modules)()
+			module.SetPluginStdin(os.Stdin)
 			return tui.RunHeadless(modules, os.Stdout)
 		}
 
-		// Interactive TUI
-		m := tui.New()
+		// Interactive TUI. Passphrases and the sudo password are asked for
+		// on the released terminal once the modules to install are known.
+		release := func() {}
+		defer func() { release() }()
+		m := tui.New(func(modules []module.Module) error {
+			if err := module.PromptAll(modules); err != nil {
+				return err
+			}
+			release = acquireSudo(modules)
+			return nil
+		})
 		p := tea.NewProgram(m, tea.WithAltScreen())
 		if _, err := p.Run(); err != nil {
 			return fmt.Errorf("TUI error: %w", err)
@@ -64,3 +126,56 @@ Examples:
 		return nil
 	},
 }
+
+// acquireSudo asks for the sudo password once when any of the modules needs
+// it and keeps the ticket alive until the returned function is called.
+func acquireSudo(modules []module.Module) func() {
+	needs := module.SudoModules(modules)
+	if len(needs) == 0 {
+		return func() {}
+	}
+
+	var names []string
+	for _, m := range needs {
+		names = append(names, m.Name())
+	}
+	fmt.Fprintf(os.Stderr, "Administrator privileges needed for: %s\n", strings.Join(names, ", "))
+
+	stop, err := module.AcquireSudo()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Continuing without sudo (privileged steps will be skipped): %v\n", err)
+	}
+	return stop
+}
+
+// preflight prints the preflight report for modules. Failing modules abort
+// the run, or with --skip-failing are removed along with their dependents.
+func preflight(modules []module.Module) ([]module.Module, error) {
+	reports := module.Preflight(modules)
+	if len(reports) == 0 {
+		return modules, nil
+	}
+
+	fmt.Fprintln(os.Stderr, "Preflight:")
+	var failed []string
+	for _, r := range reports {
+		if r.Failed() {
+			failed = append(failed, r.Module.ID())
+		}
+		for _, res := range r.Results {
+			fmt.Fprintf(os.Stderr, "  %s %s: %s\n", severityIcon(res.Severity), r.Module.Name(), res.Message)
+			if res.Fix != "" {
+				fmt.Fprintf(os.Stderr, "      fix: %s\n", res.Fix)
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return modules, nil
+	}
+	if !skipFailingFlag {
+		return nil, fmt.Errorf("preflight failed for %s (use --skip-failing to install the rest)", strings.Join(failed, ", "))
+	}
+	kept := module.Exclude(modules, failed)
+	fmt.Fprintf(os.Stderr, "Skipping %d module(s)\n", len(modules)-len(kept))
+	return kept, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+// preflighter is a module with fixed preflight results.
+type preflighter struct {
+	id      string
+	deps    []string
+	results []module.Result
+}
+
+func (p *preflighter) Name() string              { return p.id }
+func (p *preflighter) ID() string                { return p.id }
+func (p *preflighter) Description() string       { return "" }
+func (p *preflighter) Dependencies() []string    { return p.deps }
+func (p *preflighter) Install(w io.Writer) error { return nil }
+func (p *preflighter) Preflight() []module.Result {
+	return p.results
+}
+
+func useSkipFailing(t *testing.T, skip bool) {
+	t.Helper()
+	skipFailingFlag = skip
+	t.Cleanup(func() { skipFailingFlag = false })
+}
+
+func moduleIDs(modules []module.Module) []string {
+	var out []string
+	for _, m := range modules {
+		out = append(out, m.ID())
+	}
+	return out
+}
+
+// preflightModules returns, in install order, a module that fails preflight
+// (npm), one depending on it (claude), one that only warns (ghostty) and
+// one that passes (fish).
+func preflightModules() []module.Module {
+	return []module.Module{
+		&preflighter{id: "fish", results: []module.Result{{Severity: module.Pass, Message: "ok"}}},
+		&preflighter{id: "npm", results: []module.Result{{Severity: module.Fail, Message: "node not found", Fix: "install node"}}},
+		&preflighter{id: "claude", deps: []string{"npm"}},
+		&preflighter{id: "ghostty", results: []module.Result{{Severity: module.Warn, Message: "only 3.0 GB free"}}},
+	}
+}
+
+func TestPreflightAbortsOnFailure(t *testing.T) {
+	useSkipFailing(t, false)
+	kept, err := preflight(preflightModules())
+	if err == nil || kept != nil {
+		t.Fatalf("preflight = %v, %v; want an error", moduleIDs(kept), err)
+	}
+	if !strings.Contains(err.Error(), "preflight failed for npm") || !strings.Contains(err.Error(), "--skip-failing") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestPreflightSkipFailing(t *testing.T) {
+	useSkipFailing(t, true)
+	kept, err := preflight(preflightModules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// npm fails and claude depends on it; warnings do not drop a module.
+	if got, want := moduleIDs(kept), []string{"fish", "ghostty"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept = %v, want %v", got, want)
+	}
+}
+
+func TestPreflightWarningsOnly(t *testing.T) {
+	useSkipFailing(t, false)
+	modules := preflightModules()
+	modules = []module.Module{modules[0], modules[3]}
+	kept, err := preflight(modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(kept, modules) {
+		t.Errorf("kept = %v, want every module", moduleIDs(kept))
+	}
+}
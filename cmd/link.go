@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+	rootCmd.AddCommand(unlinkCmd)
+}
+
+var linkCmd = &cobra.Command{
+	Use:   "link <repo-path> [modules...]",
+	Short: "Convert managed config files to symlinks into a repo checkout",
+	Long: `Replace managed config files with symlinks into a local checkout of the
+henrik-os repository, so edits in the checkout take effect without a rebuild.
+Existing files are backed up to <path>.bak first.
+
+Examples:
+  henrik-os link ~/dev/henrik-os          # All modules
+  henrik-os link ~/dev/henrik-os fish     # Just fish`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveFilterDirs
+		}
+		return completeModuleIDs(cmd, args, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := module.SetLinkRoot(args[0]); err != nil {
+			return err
+		}
+		modules, err := modulesByID(args[1:])
+		if err != nil {
+			return err
+		}
+		for _, m := range modules {
+			fp, ok := m.(module.FileProvider)
+			if !ok {
+				continue
+			}
+			for _, f := range fp.Files() {
+				if err := module.LinkFile(os.Stdout, f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var unlinkCmd = &cobra.Command{
+	Use:   "unlink [modules...]",
+	Short: "Convert symlinked config files back into copies",
+	Long: `Replace symlinked config files with regular copies of their current
+content. Files that are not symlinks, and symlinks that do not point into a
+checkout, are left untouched.`,
+	ValidArgsFunction: completeModuleIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modules, err := modulesByID(args)
+		if err != nil {
+			return err
+		}
+		for _, m := range modules {
+			fp, ok := m.(module.FileProvider)
+			if !ok {
+				continue
+			}
+			for _, f := range fp.Files() {
+				if err := module.UnlinkFile(os.Stdout, f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/henrikkvamme/henrik-os/configs"
+)
+
+func TestLinkUnlinkStatus(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Cleanup(func() { linkFlag = "" })
+
+	// A checkout holding the starship config.
+	checkout := t.TempDir()
+	src := filepath.Join(checkout, "configs/starship/starship.toml")
+	data, err := configs.FS.ReadFile("starship/starship.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(src), 0o755)
+	if err := os.WriteFile(src, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(home, ".config/starship.toml")
+
+	status := func(want string) {
+		t.Helper()
+		out, err := execute(t, "status", "starship")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(out, "\n") {
+			if strings.Contains(line, dest) && strings.HasPrefix(strings.TrimSpace(line), want+" ") {
+				return
+			}
+		}
+		t.Errorf("status =\n%s\nwant %q", out, want)
+	}
+
+	status("missing")
+
+	if _, err := execute(t, "link", checkout, "starship"); err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := os.Readlink(dest); target != src {
+		t.Fatalf("%s links to %q, want %q", dest, target, src)
+	}
+	status("linked")
+
+	// Edits in the checkout are kept when unlinking.
+	os.WriteFile(src, append(data, "# edited\n"...), 0o644)
+	if _, err := execute(t, "unlink", "starship"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !strings.HasSuffix(string(got), "# edited\n") {
+		t.Errorf("unlinked copy lost the checkout edit")
+	}
+	status("modified")
+	os.WriteFile(dest, data, 0o644)
+	status("copy")
+
+	// Links pointing elsewhere are reported and never unlinked.
+	other := filepath.Join(t.TempDir(), "starship.toml")
+	os.WriteFile(other, data, 0o644)
+	os.Remove(dest)
+	os.Symlink(other, dest)
+	status("foreign link")
+	out, err := execute(t, "unlink", "starship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := os.Readlink(dest); target != other || !strings.Contains(out, "Skipping "+dest) {
+		t.Errorf("unlink replaced a foreign link (now %q):\n%s", target, out)
+	}
+
+	// A link into a checkout that lost the file is broken, and unlinking
+	// restores the config henrik-os ships.
+	os.Remove(dest)
+	os.Symlink(src, dest)
+	os.Remove(src)
+	status("broken link")
+	if _, err := execute(t, "unlink", "starship"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != string(data) {
+		t.Error("unlinking a broken link did not restore the shipped config")
+	}
+	status("copy")
+}
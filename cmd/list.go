@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+var listJSON bool
+
+func init() {
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print modules as JSON")
+	rootCmd.AddCommand(listCmd)
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available modules",
+	Long: `List every registered module with its dependencies, tags and
+install status. Status is derived from the module's own checks and the
+state of the files it manages.
+
+Examples:
+  henrik-os list
+  henrik-os list --json | jq '.[] | select(.status != "installed") | .id'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type entry struct {
+			ID           string   `json:"id"`
+			Name         string   `json:"name"`
+			Description  string   `json:"description"`
+			Dependencies []string `json:"dependencies"`
+			Tags         []string `json:"tags"`
+			Status       string   `json:"status"`
+		}
+		var entries []entry
+		for _, m := range module.All() {
+			entries = append(entries, entry{
+				ID:           m.ID(),
+				Name:         m.Name(),
+				Description:  m.Description(),
+				Dependencies: nonNil(m.Dependencies()),
+				Tags:         nonNil(module.Tags(m)),
+				Status:       moduleStatus(m),
+			})
+		}
+
+		if listJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tDEPENDENCIES\tTAGS\tSTATUS")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Name, orDash(e.Dependencies), orDash(e.Tags), e.Status)
+		}
+		return tw.Flush()
+	},
+}
+
+// moduleStatus summarizes whether m is installed: "unsupported" on other
+// platforms, otherwise from its Checker and the state of its files.
+func moduleStatus(m module.Module) string {
+	if !module.Supported(m) {
+		return "unsupported"
+	}
+	checked := false
+	if c, ok := m.(module.Checker); ok {
+		err := c.Check()
+		if err != nil && !errors.Is(err, module.ErrNoCheck) {
+			return "not installed"
+		}
+		checked = err == nil
+	}
+
+	fp, ok := m.(module.FileProvider)
+	if !ok || len(fp.Files()) == 0 {
+		if checked {
+			return "installed"
+		}
+		return "unknown"
+	}
+
+	deployed, missing := 0, 0
+	for _, f := range fp.Files() {
+		switch state, _ := module.Status(f); state {
+		case module.StateCopy, module.StateLinked, module.StateBlock, module.StateMerged:
+			deployed++
+		case module.StateMissing:
+			missing++
+		default:
+			return "modified"
+		}
+	}
+	switch {
+	case missing == 0:
+		return "installed"
+	case deployed == 0:
+		return "not installed"
+	}
+	return "partial"
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func orDash(s []string) string {
+	if len(s) == 0 {
+		return "-"
+	}
+	return strings.Join(s, ", ")
+}
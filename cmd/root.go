@@ -3,19 +3,45 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+	"github.com/henrikkvamme/henrik-os/selfupdate"
 )
 
 var version = "dev"
 
 var rootCmd = &cobra.Command{
 	Use:     "henrik-os",
-	Short:   "Henrik OS - Mac Development Environment Setup",
+	Short:   "Henrik OS - Development Environment Setup for macOS and Linux",
 	Version: version,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if cmd.Hidden || cmd.Name() == "self-update" || cmd.Name() == "completion" ||
+			(cmd.HasParent() && cmd.Parent().Name() == "completion") || !module.UpdateCheck() {
+			return
+		}
+		if notice := selfupdate.Notice(version); notice != "" {
+			fmt.Fprintln(os.Stderr, "\n"+notice)
+		}
+	},
 }
 
+// manifestErr is the error loading config.toml. Read-only commands run with
+// the defaults and a warning; install refuses to run.
+var manifestErr error
+
 func Execute() {
+	if manifestErr = module.LoadManifest(module.ManifestPath()); manifestErr != nil {
+		fmt.Fprintln(os.Stderr, "warning:", manifestErr)
+	}
+	for _, err := range module.LoadDeclarative(filepath.Join(module.ConfigDir(), "modules")) {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
+	for _, err := range module.LoadPlugins() {
+		fmt.Fprintln(os.Stderr, "warning:", err)
+	}
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
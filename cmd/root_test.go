@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+// execute runs henrik-os with args and returns what it printed to stdout.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("HENRIK_OS_NO_UPDATE_CHECK", "1")
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+
+	data, _ := os.ReadFile(out.Name())
+	return string(data), err
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/selfupdate"
+)
+
+var (
+	selfUpdateCheckFlag bool
+	selfUpdateForceFlag bool
+)
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheckFlag, "check", false, "Only report whether an update is available")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForceFlag, "force", false, "Install the latest release even if it is not newer")
+	rootCmd.AddCommand(selfUpdateCmd)
+}
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update henrik-os to the latest release",
+	Long: `Download the latest henrik-os release from GitHub, verify it against the
+release checksums and replace the running binary.
+
+Installs managed by Homebrew are left alone; use brew upgrade instead.
+Set HENRIK_OS_RELEASES_URL to use a different releases API.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := selfupdate.New()
+		release, err := u.Latest()
+		if err != nil {
+			return err
+		}
+
+		newer := selfupdate.Newer(release.Version(), version)
+		if !newer && !selfUpdateForceFlag {
+			fmt.Printf("henrik-os %s is up to date (latest release %s)\n", version, release.Version())
+			return nil
+		}
+		if selfUpdateCheckFlag {
+			fmt.Printf("henrik-os %s is available (you have %s)\n", release.Version(), version)
+			return nil
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("locating executable: %w", err)
+		}
+		if selfupdate.HomebrewManaged(exe) {
+			return fmt.Errorf("henrik-os is managed by Homebrew; run `brew upgrade henrik-os` instead")
+		}
+
+		fmt.Printf("Updating henrik-os %s → %s...\n", version, release.Version())
+		if err := u.Apply(release, exe); err != nil {
+			return err
+		}
+		fmt.Printf("Updated %s\n", exe)
+		return nil
+	},
+}
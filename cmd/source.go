@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+	"github.com/henrikkvamme/henrik-os/source"
+)
+
+var (
+	sourceFlag         string
+	sourcePinFlag      string
+	sourceChecksumFlag string
+)
+
+// addSourceFlags registers the --source flags on commands that read the
+// configs tree.
+func addSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sourceFlag, "source", "", "Use configs from a git URL, local path or https tarball instead of the built-in ones")
+	cmd.Flags().StringVar(&sourcePinFlag, "source-pin", "", "Commit the git --source must be at")
+	cmd.Flags().StringVar(&sourceChecksumFlag, "source-checksum", "", "Expected sha256 of the tarball --source")
+}
+
+// applySource fetches the --source tree, if any, and makes modules deploy
+// from it.
+func applySource() error {
+	if sourceFlag == "" {
+		return nil
+	}
+	dir, err := source.Fetch(os.Stderr, sourceFlag, source.Options{
+		Pin:      sourcePinFlag,
+		Checksum: sourceChecksumFlag,
+	})
+	if err != nil {
+		return err
+	}
+	module.SetSource(os.DirFS(dir))
+	return nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+func init() {
+	statusCmd.Flags().StringVar(&linkFlag, "link", "", "Expect symlinks into this repo checkout")
+	addSourceFlags(statusCmd)
+	rootCmd.AddCommand(statusCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [modules...]",
+	Short: "Show the state of managed config files",
+	Long: `Show whether each managed config file is a copy, a link into a repo
+checkout, locally modified, missing, or a broken/foreign symlink.
+
+Examples:
+  henrik-os status                       # All modules
+  henrik-os status fish neovim           # Specific modules
+  henrik-os status --link ~/dev/henrik-os`,
+	ValidArgsFunction: completeModuleIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if linkFlag != "" {
+			if err := module.SetLinkRoot(linkFlag); err != nil {
+				return err
+			}
+		}
+		if err := applySource(); err != nil {
+			return err
+		}
+		modules, err := modulesByID(args)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range modules {
+			fp, ok := m.(module.FileProvider)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "\n%s\n", m.Name())
+			for _, f := range fp.Files() {
+				state, target := module.Status(f)
+				if target != "" {
+					fmt.Fprintf(os.Stdout, "  %-13s %s → %s\n", state, f.Dest, target)
+				} else {
+					fmt.Fprintf(os.Stdout, "  %-13s %s\n", state, f.Dest)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// modulesByID returns the modules matching the given selectors, or every
+// module when args is empty. Dependencies are not resolved.
+func modulesByID(args []string) ([]module.Module, error) {
+	if len(args) == 0 {
+		return module.Available(), nil
+	}
+	ids, err := module.Select(args)
+	if err != nil {
+		return nil, err
+	}
+	var modules []module.Module
+	for _, id := range ids {
+		modules = append(modules, module.ByID(id))
+	}
+	return modules, nil
+}
+
+func completeModuleIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var comps []string
+	for _, m := range module.Available() {
+		comps = append(comps, m.ID()+"\t"+m.Description())
+	}
+	for _, c := range module.Categories {
+		if c == "other" {
+			continue
+		}
+		comps = append(comps, "tag:"+c+"\t"+module.CategoryName(c)+" modules")
+	}
+	comps = append(comps, "all\tEvery available module")
+	return comps, cobra.ShellCompDirectiveNoFileComp
+}
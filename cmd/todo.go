@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+var todoAll bool
+
+func init() {
+	todoCmd.Flags().BoolVar(&todoAll, "all", false, "Also show steps that are already done")
+	rootCmd.AddCommand(todoCmd)
+}
+
+var todoCmd = &cobra.Command{
+	Use:   "todo [modules...]",
+	Short: "Show manual steps that are still left",
+	Long: `Show the follow-up steps of installed modules that henrik-os cannot do
+for you, such as signing in to GitHub or apps. Steps that can be detected
+as done (e.g. gh auth status) are hidden unless --all is given.
+
+Examples:
+  henrik-os todo
+  henrik-os todo --all
+  henrik-os todo homebrew`,
+	ValidArgsFunction: completeModuleIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modules, err := modulesByID(args)
+		if err != nil {
+			return err
+		}
+
+		printTodo(os.Stdout, modules)
+		return nil
+	},
+}
+
+// printTodo prints the numbered steps of the installed modules. Done steps
+// are left out, and not counted, unless --all is given.
+func printTodo(w io.Writer, modules []module.Module) {
+	// Modules whose status cannot be told are left out, so their steps
+	// do not show up for something that was never installed.
+	var installed []module.Module
+	for _, m := range modules {
+		if s := moduleStatus(m); s != "not installed" && s != "unsupported" && s != "unknown" {
+			installed = append(installed, m)
+		}
+	}
+
+	n := 0
+	for _, step := range module.Steps(installed) {
+		done := step.IsDone()
+		if done && !todoAll {
+			continue
+		}
+		n++
+		mark := " "
+		if done {
+			mark = "✓"
+		}
+		fmt.Fprintf(w, "  %s %d. %s: %s\n", mark, n, step.Title, step.Action)
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "Nothing left to do")
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+// stepModule is a module with manual steps and a fixed check result.
+type stepModule struct {
+	id        string
+	check     error
+	platforms []string
+	steps     []module.Step
+}
+
+func (s *stepModule) Name() string              { return s.id }
+func (s *stepModule) ID() string                { return s.id }
+func (s *stepModule) Description() string       { return "" }
+func (s *stepModule) Dependencies() []string    { return nil }
+func (s *stepModule) Platforms() []string       { return s.platforms }
+func (s *stepModule) Install(w io.Writer) error { return nil }
+func (s *stepModule) Check() error              { return s.check }
+func (s *stepModule) Steps() []module.Step      { return s.steps }
+
+func step(title string, done bool) module.Step {
+	return module.Step{Title: title, Action: "do " + title, Done: func() bool { return done }}
+}
+
+func todoModules() []module.Module {
+	return []module.Module{
+		&stepModule{id: "gh", steps: []module.Step{step("GitHub", true), step("Copilot", false)}},
+		&stepModule{id: "missing", check: errors.New("not found"), steps: []module.Step{step("Missing", false)}},
+		&stepModule{id: "unknown", check: module.ErrNoCheck, steps: []module.Step{step("Unknown", false)}},
+		&stepModule{id: "other-os", platforms: []string{"plan9"}, steps: []module.Step{step("Other OS", false)}},
+		&stepModule{id: "app", steps: []module.Step{{Title: "App", Action: "sign in"}, step("Done", true)}},
+	}
+}
+
+func useTodoAll(t *testing.T, all bool) {
+	t.Helper()
+	todoAll = all
+	t.Cleanup(func() { todoAll = false })
+}
+
+func TestPrintTodo(t *testing.T) {
+	useTodoAll(t, false)
+	var out strings.Builder
+	printTodo(&out, todoModules())
+
+	// Done steps are neither shown nor counted; steps without Done are
+	// never known to be done.
+	want := "    1. Copilot: do Copilot\n" +
+		"    2. App: sign in\n"
+	if out.String() != want {
+		t.Errorf("todo =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPrintTodoAll(t *testing.T) {
+	useTodoAll(t, true)
+	var out strings.Builder
+	printTodo(&out, todoModules())
+
+	// Modules that are not installed, unsupported or of unknown status
+	// are still left out.
+	want := "  ✓ 1. GitHub: do GitHub\n" +
+		"    2. Copilot: do Copilot\n" +
+		"    3. App: sign in\n" +
+		"  ✓ 4. Done: do Done\n"
+	if out.String() != want {
+		t.Errorf("todo --all =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestPrintTodoNothingLeft(t *testing.T) {
+	useTodoAll(t, false)
+	var out strings.Builder
+	printTodo(&out, []module.Module{
+		&stepModule{id: "gh", steps: []module.Step{step("GitHub", true)}},
+		&stepModule{id: "missing", check: errors.New("not found"), steps: []module.Step{step("Missing", false)}},
+	})
+	if out.String() != "Nothing left to do\n" {
+		t.Errorf("todo = %q", out.String())
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+var (
+	updateYesFlag    bool
+	updateDryRunFlag bool
+)
+
+func init() {
+	updateCmd.Flags().BoolVarP(&updateYesFlag, "yes", "y", false, "Apply updates without asking")
+	updateCmd.Flags().BoolVar(&updateDryRunFlag, "dry-run", false, "Only show what would change")
+	rootCmd.AddCommand(updateCmd)
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update [modules...]",
+	Short: "Upgrade packages, extensions, Node and plugins",
+	Long: `Bring installed modules up to date. Every module that supports updating
+first reports what it would change; nothing is applied until confirmed.
+
+Examples:
+  henrik-os update                 # Preview, confirm, apply
+  henrik-os update --dry-run       # Preview only
+  henrik-os update homebrew -y     # Upgrade managed brew packages without asking`,
+	ValidArgsFunction: completeModuleIDs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modules := module.Available()
+		if len(args) > 0 {
+			var err error
+			if modules, err = modulesByID(args); err != nil {
+				return err
+			}
+		}
+		return runUpdate(os.Stdout, os.Stdin, modules)
+	},
+}
+
+// runUpdate prints the update plan of every module supported on this
+// platform, asks on in whether to apply it unless --yes or --dry-run is
+// given, and updates the modules with a non-empty plan.
+func runUpdate(w io.Writer, in io.Reader, modules []module.Module) error {
+	var pending []module.Module
+	for _, m := range modules {
+		u, ok := m.(module.Updater)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "\n══ %s ══\n", m.Name())
+		if hint := module.SupportHint(m); hint != "" {
+			fmt.Fprintf(w, "  skipped: %s\n", hint)
+			continue
+		}
+		plan, err := u.PlanUpdate()
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "  skipped: %v\n", err)
+		case len(plan) == 0:
+			fmt.Fprintln(w, "  up to date")
+		default:
+			for _, line := range plan {
+				fmt.Fprintf(w, "  %s\n", line)
+			}
+			pending = append(pending, m)
+		}
+	}
+	fmt.Fprintln(w)
+
+	if len(pending) == 0 {
+		fmt.Fprintln(w, "Nothing to update.")
+		return nil
+	}
+	if updateDryRunFlag {
+		return nil
+	}
+	if !updateYesFlag {
+		fmt.Fprint(w, "Apply these updates? [y/N] ")
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		if strings.TrimSpace(strings.ToLower(answer)) != "y" {
+			fmt.Fprintln(w, "Aborted.")
+			return nil
+		}
+	}
+
+	var failed []string
+	for _, m := range pending {
+		fmt.Fprintf(w, "\n══ %s ══\n", m.Name())
+		if err := m.(module.Updater).Update(w); err != nil {
+			fmt.Fprintf(w, "  ✗ Failed: %v\n", err)
+			failed = append(failed, m.Name())
+		} else {
+			fmt.Fprintln(w, "  ✓ Updated")
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("update failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
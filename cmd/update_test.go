@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/henrikkvamme/henrik-os/module"
+)
+
+// updater is a module with a fixed update plan that records its updates.
+type updater struct {
+	id        string
+	platforms []string
+	planned   bool
+	plan      []string
+	planErr   error
+	err       error
+	updated   bool
+}
+
+func (u *updater) Name() string              { return u.id }
+func (u *updater) ID() string                { return u.id }
+func (u *updater) Description() string       { return "" }
+func (u *updater) Dependencies() []string    { return nil }
+func (u *updater) Platforms() []string       { return u.platforms }
+func (u *updater) Install(w io.Writer) error { return nil }
+func (u *updater) PlanUpdate() ([]string, error) {
+	u.planned = true
+	return u.plan, u.planErr
+}
+func (u *updater) Update(w io.Writer) error {
+	u.updated = true
+	return u.err
+}
+
+func useUpdateFlags(t *testing.T, yes, dryRun bool) {
+	t.Helper()
+	updateYesFlag, updateDryRunFlag = yes, dryRun
+	t.Cleanup(func() { updateYesFlag, updateDryRunFlag = false, false })
+}
+
+func TestRunUpdatePlan(t *testing.T) {
+	useUpdateFlags(t, false, true)
+	current := &updater{id: "current"}
+	stale := &updater{id: "stale", plan: []string{"upgrade fd 9.0 → 10.1"}}
+	broken := &updater{id: "broken", planErr: errors.New("nvim is not installed")}
+
+	var out strings.Builder
+	if err := runUpdate(&out, strings.NewReader("y\n"), []module.Module{current, stale, broken}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"══ current ══\n  up to date",
+		"══ stale ══\n  upgrade fd 9.0 → 10.1",
+		"══ broken ══\n  skipped: nvim is not installed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+	if stale.updated {
+		t.Error("--dry-run applied the update")
+	}
+}
+
+func TestRunUpdateConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		yes     bool
+		answer  string
+		updated bool
+	}{
+		{"confirmed", false, "y\n", true},
+		{"confirmed in capitals", false, " Y \n", true},
+		{"declined", false, "n\n", false},
+		{"no answer", false, "", false},
+		{"--yes", true, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useUpdateFlags(t, tt.yes, false)
+			m := &updater{id: "stale", plan: []string{"upgrade fd"}}
+			var out strings.Builder
+			if err := runUpdate(&out, strings.NewReader(tt.answer), []module.Module{m}); err != nil {
+				t.Fatal(err)
+			}
+			if m.updated != tt.updated {
+				t.Errorf("updated = %v, want %v", m.updated, tt.updated)
+			}
+			// The prompt goes to the same output the answer is asked for.
+			if asked := strings.Contains(out.String(), "Apply these updates? [y/N] "); asked == tt.yes {
+				t.Errorf("prompted = %v with --yes = %v:\n%s", asked, tt.yes, out.String())
+			}
+		})
+	}
+}
+
+func TestRunUpdateUnsupported(t *testing.T) {
+	useUpdateFlags(t, false, false)
+	other := &updater{id: "other-os", platforms: []string{"plan9"}, plan: []string{"upgrade"}}
+	var out strings.Builder
+	if err := runUpdate(&out, nil, []module.Module{other}); err != nil {
+		t.Fatal(err)
+	}
+	if other.planned {
+		t.Error("planned an update for a module unsupported on this platform")
+	}
+	if !strings.Contains(out.String(), "skipped: plan9 only") || !strings.Contains(out.String(), "Nothing to update.") {
+		t.Errorf("output =\n%s", out.String())
+	}
+}
+
+func TestRunUpdateNothingToDo(t *testing.T) {
+	useUpdateFlags(t, false, false)
+	// An empty plan never asks, so an unreadable input is fine.
+	var out strings.Builder
+	if err := runUpdate(&out, nil, []module.Module{&updater{id: "current"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Nothing to update.") {
+		t.Errorf("output =\n%s", out.String())
+	}
+}
+
+func TestRunUpdateFailure(t *testing.T) {
+	useUpdateFlags(t, true, false)
+	failing := &updater{id: "failing", plan: []string{"x"}, err: errors.New("network down")}
+	ok := &updater{id: "ok", plan: []string{"y"}}
+	var out strings.Builder
+	err := runUpdate(&out, nil, []module.Module{failing, ok})
+	if err == nil || err.Error() != "update failed: failing" {
+		t.Errorf("runUpdate = %v", err)
+	}
+	if !ok.updated || !strings.Contains(out.String(), "✗ Failed: network down") {
+		t.Errorf("a failing module stopped the others:\n%s", out.String())
+	}
+}
@@ -0,0 +1,236 @@
+// Package github uploads SSH keys to a GitHub account through the REST API.
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the GitHub REST API.
+const DefaultBaseURL = "https://api.github.com"
+
+// KeyKind selects which list of SSH keys a call works on.
+type KeyKind string
+
+const (
+	AuthKey    KeyKind = "keys"             // used for git over SSH
+	SigningKey KeyKind = "ssh_signing_keys" // used to verify signed commits
+)
+
+func (k KeyKind) String() string {
+	if k == SigningKey {
+		return "signing key"
+	}
+	return "authentication key"
+}
+
+// Client talks to the GitHub API as the owner of Token.
+type Client struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+}
+
+// New returns a Client using HENRIK_OS_GITHUB_API or DefaultBaseURL and the
+// token from Token. It returns an error when no token is available.
+func New() (*Client, error) {
+	token := Token()
+	if token == "" {
+		return nil, fmt.Errorf("no GitHub token: set GITHUB_TOKEN or GH_TOKEN, or run gh auth login")
+	}
+	base := os.Getenv("HENRIK_OS_GITHUB_API")
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return &Client{BaseURL: base, Token: token, HTTP: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Token returns a GitHub token from GITHUB_TOKEN, GH_TOKEN or the gh CLI,
+// or "" when none is available.
+func Token() string {
+	for _, key := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if t := os.Getenv(key); t != "" {
+			return t
+		}
+	}
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Key is an SSH key registered on the account.
+type Key struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Key   string `json:"key"`
+}
+
+// Keys lists the account's keys of the given kind, following the Link
+// header through every page.
+func (c *Client) Keys(kind KeyKind) ([]Key, error) {
+	var keys []Key
+	path := "/user/" + string(kind) + "?per_page=100"
+	for path != "" {
+		var page []Key
+		header, err := c.request("GET", path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+		path = nextPage(header.Get("Link"))
+	}
+	return keys, nil
+}
+
+// nextPage returns the rel="next" URL of a Link header, or "".
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(part, ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		target = strings.TrimSpace(target)
+		return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+	}
+	return ""
+}
+
+// AddKey registers a public key.
+func (c *Client) AddKey(kind KeyKind, title, key string) error {
+	body := map[string]string{"title": title, "key": key}
+	return c.do("POST", "/user/"+string(kind), body, nil)
+}
+
+// EnsureKey uploads the public key unless the account already has it.
+// Keys are compared by type and key material, ignoring the comment.
+func (c *Client) EnsureKey(w io.Writer, kind KeyKind, title, pubKey string) error {
+	if keyMaterial(pubKey) == "" {
+		return fmt.Errorf("invalid public key")
+	}
+	has, err := c.HasKey(kind, pubKey)
+	if err != nil {
+		return err
+	}
+	if has {
+		fmt.Fprintf(w, "  SSH %s already on GitHub\n", kind)
+		return nil
+	}
+	if err := c.AddKey(kind, title, strings.TrimSpace(pubKey)); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  Added SSH %s to GitHub as %q\n", kind, title)
+	return nil
+}
+
+// HasKey reports whether the account has the public key.
+func (c *Client) HasKey(kind KeyKind, pubKey string) (bool, error) {
+	keys, err := c.Keys(kind)
+	if err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if keyMaterial(k.Key) == keyMaterial(pubKey) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keyMaterial returns the "type base64" part of an authorized_keys line.
+func keyMaterial(pubKey string) string {
+	fields := strings.Fields(pubKey)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[0] + " " + fields[1]
+}
+
+// scopeHint tells how to grant the token the scopes the key endpoints need.
+// GitHub answers 404 rather than 403 when a token lacks them.
+const scopeHint = "the token may lack the key scopes; run: gh auth refresh -s admin:public_key,admin:ssh_signing_key"
+
+func (c *Client) do(method, path string, body, out any) error {
+	_, err := c.request(method, path, body, out)
+	return err
+}
+
+// request sends a request to path, relative to BaseURL, or to an absolute
+// URL such as a Link header target, and returns the response headers.
+// Absolute URLs must have the scheme and host of BaseURL, so the token is
+// never sent anywhere else.
+func (c *Client) request(method, path string, body, out any) (http.Header, error) {
+	if !strings.HasPrefix(path, "/") && !c.sameOrigin(path) {
+		return nil, fmt.Errorf("%s %s: not sending the token outside %s", method, path, c.BaseURL)
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	target := path
+	if strings.HasPrefix(path, "/") {
+		target = strings.TrimRight(c.BaseURL, "/") + path
+	}
+	req, err := http.NewRequest(method, target, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		msg := resp.Status
+		if e.Message != "" {
+			msg += " (" + e.Message + ")"
+		}
+		if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound {
+			msg += "; " + scopeHint
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, msg)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, fmt.Errorf("parsing %s response: %w", path, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// sameOrigin reports whether rawURL has the scheme and host of BaseURL.
+func (c *Client) sameOrigin(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == base.Scheme && u.Host == base.Host
+}
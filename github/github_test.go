@@ -0,0 +1,182 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const pubKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIKey me@laptop"
+
+// stub is a GitHub API serving the key lists of one account.
+type stub struct {
+	keys  map[KeyKind][]Key
+	posts map[KeyKind][]map[string]string
+	// status, when set, is returned for every request.
+	status int
+}
+
+func newStub(t *testing.T, s *stub) *Client {
+	t.Helper()
+	if s.posts == nil {
+		s.posts = map[KeyKind][]map[string]string{}
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, `{"message": "Requires authentication"}`, http.StatusUnauthorized)
+			return
+		}
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		kind := KeyKind(strings.TrimPrefix(r.URL.Path, "/user/"))
+		if r.Method == "POST" {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			s.posts[kind] = append(s.posts[kind], body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{}`)
+			return
+		}
+
+		// One key per page, linking to the next.
+		page := 1
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		keys := s.keys[kind]
+		if page < len(keys) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=%d>; rel="next", <%s%s?page=%d>; rel="last"`,
+				srv.URL, r.URL.Path, page+1, srv.URL, r.URL.Path, len(keys)))
+		}
+		var out []Key
+		if page <= len(keys) {
+			out = keys[page-1 : page]
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, Token: "token", HTTP: srv.Client()}
+}
+
+func TestKeysFollowsPages(t *testing.T) {
+	c := newStub(t, &stub{keys: map[KeyKind][]Key{
+		AuthKey: {{ID: 1, Key: "ssh-rsa AAAA1"}, {ID: 2, Key: "ssh-rsa AAAA2"}, {ID: 3, Key: "ssh-rsa AAAA3"}},
+	}})
+	keys, err := c.Keys(AuthKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, k := range keys {
+		ids = append(ids, k.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("Keys returned ids %v, want [1 2 3]", ids)
+	}
+}
+
+func TestKeysStaysOnBaseHost(t *testing.T) {
+	var leaked bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization") != ""
+		fmt.Fprint(w, `[]`)
+	}))
+	t.Cleanup(other.Close)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s/user/keys?page=2>; rel="next"`, other.URL))
+		fmt.Fprint(w, `[{"id": 1, "key": "ssh-rsa AAAA1"}]`)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := &Client{BaseURL: srv.URL, Token: "token", HTTP: srv.Client()}
+	if _, err := c.Keys(AuthKey); err == nil || !strings.Contains(err.Error(), "not sending the token") {
+		t.Errorf("Keys = %v, want an error for the foreign next page", err)
+	}
+	if leaked {
+		t.Error("the token was sent to another host")
+	}
+}
+
+func TestEnsureKey(t *testing.T) {
+	t.Run("already registered with another comment", func(t *testing.T) {
+		s := &stub{keys: map[KeyKind][]Key{
+			AuthKey: {{ID: 1, Key: "ssh-rsa AAAAother"}, {ID: 2, Key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIKey"}},
+		}}
+		c := newStub(t, s)
+		var out strings.Builder
+		if err := c.EnsureKey(&out, AuthKey, "laptop", pubKey); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.posts[AuthKey]) != 0 {
+			t.Errorf("uploaded a key that was already registered: %v", s.posts)
+		}
+		if !strings.Contains(out.String(), "already on GitHub") {
+			t.Errorf("output %q", out.String())
+		}
+	})
+
+	t.Run("uploads a missing key", func(t *testing.T) {
+		s := &stub{keys: map[KeyKind][]Key{AuthKey: {{ID: 1, Key: "ssh-rsa AAAAother"}}}}
+		c := newStub(t, s)
+		if err := c.EnsureKey(io.Discard, AuthKey, "laptop", pubKey+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		want := []map[string]string{{"title": "laptop", "key": pubKey}}
+		if fmt.Sprint(s.posts[AuthKey]) != fmt.Sprint(want) {
+			t.Errorf("posted %v, want %v", s.posts[AuthKey], want)
+		}
+	})
+
+	t.Run("signing key endpoint", func(t *testing.T) {
+		s := &stub{keys: map[KeyKind][]Key{AuthKey: {{ID: 1, Key: pubKey}}}}
+		c := newStub(t, s)
+		if err := c.EnsureKey(io.Discard, SigningKey, "laptop", pubKey); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.posts[SigningKey]) != 1 || len(s.posts[AuthKey]) != 0 {
+			t.Errorf("posted %v, want one signing key", s.posts)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		c := newStub(t, &stub{})
+		if err := c.EnsureKey(io.Discard, AuthKey, "laptop", "garbage"); err == nil {
+			t.Error("EnsureKey accepted an invalid public key")
+		}
+	})
+}
+
+func TestScopeHint(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusNotFound} {
+		c := newStub(t, &stub{status: status})
+		_, err := c.Keys(SigningKey)
+		if err == nil || !strings.Contains(err.Error(), "gh auth refresh -s admin:public_key,admin:ssh_signing_key") {
+			t.Errorf("status %d: error %v does not suggest refreshing the scopes", status, err)
+		}
+	}
+
+	c := newStub(t, &stub{})
+	c.Token = "wrong"
+	if _, err := c.Keys(AuthKey); err == nil || strings.Contains(err.Error(), "gh auth refresh") {
+		t.Errorf("401 error %v should not mention scopes", err)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct{ link, want string }{
+		{"", ""},
+		{`<https://api.github.com/user/keys?page=2>; rel="next", <https://api.github.com/user/keys?page=5>; rel="last"`, "https://api.github.com/user/keys?page=2"},
+		{`<https://api.github.com/user/keys?page=1>; rel="prev", <https://api.github.com/user/keys?page=1>; rel="first"`, ""},
+	}
+	for _, tt := range tests {
+		if got := nextPage(tt.link); got != tt.want {
+			t.Errorf("nextPage(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Apt is the Debian/Ubuntu package manager. Changes run through sudo.
+type Apt struct {
+	refreshed bool
+}
+
+func (a *Apt) Name() string { return "apt" }
+
+func (a *Apt) IsInstalled(pkg string) bool {
+	out, err := exec.Command("dpkg-query", "-W", "-f=${Status}", pkg).Output()
+	return err == nil && strings.Contains(string(out), "install ok installed")
+}
+
+// Refresh downloads the package index once per run. Fresh containers and CI
+// images ship without one, so nothing could be installed before it.
+func (a *Apt) Refresh(w io.Writer) error {
+	if a.refreshed {
+		return nil
+	}
+	a.refreshed = true
+	if err := SudoRun(w, "apt-get", "update"); err != nil {
+		return fmt.Errorf("apt-get update: %w", err)
+	}
+	return nil
+}
+
+// Available reports whether the package index has an installation
+// candidate for pkg.
+func (a *Apt) Available(pkg string) bool {
+	out, err := exec.Command("apt-cache", "policy", pkg).Output()
+	return err == nil && strings.Contains(string(out), "Candidate:") &&
+		!strings.Contains(string(out), "Candidate: (none)")
+}
+
+func (a *Apt) Install(w io.Writer, pkgs ...string) error {
+	return SudoRun(w, "apt-get", append([]string{"install", "-y"}, pkgs...)...)
+}
+
+func (a *Apt) Upgrade(w io.Writer, pkgs ...string) error {
+	if err := a.Refresh(w); err != nil {
+		fmt.Fprintf(w, "  %v (using the existing index)\n", err)
+	}
+	return SudoRun(w, "apt-get", append([]string{"install", "--only-upgrade", "-y"}, pkgs...)...)
+}
+
+func (a *Apt) Remove(w io.Writer, pkgs ...string) error {
+	return SudoRun(w, "apt-get", append([]string{"remove", "-y"}, pkgs...)...)
+}
+
+func (a *Apt) List() ([]string, error) {
+	out, err := exec.Command("dpkg-query", "-W", "-f=${Package}\n").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
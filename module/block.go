@@ -0,0 +1,147 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// blockMarkers returns the comment lines delimiting the managed block for id.
+func blockMarkers(id string) (begin, end string) {
+	return "# >>> henrik-os " + id + " >>>", "# <<< henrik-os " + id + " <<<"
+}
+
+// findBlock returns the line indexes of the begin and end markers for id,
+// or -1, -1 if the block is not present.
+func findBlock(lines []string, id string) (int, int) {
+	begin, end := blockMarkers(id)
+	start := -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case begin:
+			start = i
+		case end:
+			if start >= 0 {
+				return start, i
+			}
+		}
+	}
+	return -1, -1
+}
+
+// ExtractBlock returns the body of the managed block for id and whether the
+// block was found.
+func ExtractBlock(content []byte, id string) (string, bool) {
+	lines := strings.Split(string(content), "\n")
+	start, stop := findBlock(lines, id)
+	if start < 0 {
+		return "", false
+	}
+	body := strings.Join(lines[start+1:stop], "\n")
+	if body != "" {
+		body += "\n"
+	}
+	return body, true
+}
+
+// RenderBlock returns content with the managed block for id set to body.
+// An existing block is replaced in place; otherwise the block is appended.
+// An empty body removes the block. Everything outside the block is kept.
+func RenderBlock(content []byte, id, body string) []byte {
+	text := string(content)
+	lines := strings.Split(text, "\n")
+	start, stop := findBlock(lines, id)
+
+	var block []string
+	if body != "" {
+		begin, end := blockMarkers(id)
+		block = append(block, begin)
+		block = append(block, strings.Split(strings.TrimRight(body, "\n"), "\n")...)
+		block = append(block, end)
+	}
+
+	if start >= 0 {
+		var out []string
+		out = append(out, lines[:start]...)
+		out = append(out, block...)
+		out = append(out, lines[stop+1:]...)
+		return []byte(strings.Join(out, "\n"))
+	}
+
+	if len(block) == 0 {
+		return content
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if text != "" && !strings.HasSuffix(text, "\n\n") {
+		text += "\n"
+	}
+	return []byte(text + strings.Join(block, "\n") + "\n")
+}
+
+// unwrapBlock removes the markers of the managed block for id, keeping its
+// body in place as ordinary content.
+func unwrapBlock(content []byte, id string) []byte {
+	lines := strings.Split(string(content), "\n")
+	start, stop := findBlock(lines, id)
+	if start < 0 {
+		return content
+	}
+	var out []string
+	out = append(out, lines[:start]...)
+	out = append(out, lines[start+1:stop]...)
+	out = append(out, lines[stop+1:]...)
+	return []byte(strings.Join(out, "\n"))
+}
+
+// WriteBlock updates the managed block for id inside path, leaving the rest
+// of the file alone. The file is only rewritten (with backup) when the block
+// actually changes. A file that consists solely of body, as left behind by
+// an earlier whole-file install, is adopted and replaced by the block. So is
+// a file that starts with one of the previous versions of body: the block
+// takes the place of that version and whatever follows it is kept.
+func WriteBlock(w io.Writer, path, id, body string, perm os.FileMode, previous ...string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var updated []byte
+	if _, found := ExtractBlock(existing, id); found {
+		updated = RenderBlock(existing, id, body)
+	} else if strings.TrimSpace(string(existing)) == strings.TrimSpace(body) {
+		updated = RenderBlock(nil, id, body)
+	} else if rest, ok := trimPrevious(existing, previous); ok {
+		fmt.Fprintf(w, "  Replacing the earlier henrik-os content of %s\n", path)
+		updated = RenderBlock(nil, id, body)
+		if rest != "" {
+			updated = append(updated, "\n"+rest...)
+		}
+	} else {
+		updated = RenderBlock(existing, id, body)
+	}
+
+	if string(updated) == string(existing) {
+		fmt.Fprintf(w, "  Up to date %s\n", path)
+		return nil
+	}
+	return BackupAndWrite(w, path, updated, perm)
+}
+
+// trimPrevious removes the leading previous version from content and
+// returns what follows it.
+func trimPrevious(content []byte, previous []string) (string, bool) {
+	text := string(content)
+	for _, prev := range previous {
+		prev = strings.TrimRight(prev, "\n")
+		if text == prev {
+			return "", true
+		}
+		if rest, ok := strings.CutPrefix(text, prev+"\n"); ok {
+			return strings.TrimLeft(rest, "\n"), true
+		}
+	}
+	return "", false
+}
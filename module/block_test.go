@@ -0,0 +1,98 @@
+package module
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderBlock(t *testing.T) {
+	const block = "# >>> henrik-os fish >>>\n/opt/homebrew/bin/fish\n# <<< henrik-os fish <<<\n"
+	tests := []struct {
+		name     string
+		existing string
+		body     string
+		want     string
+	}{
+		{
+			name: "empty file",
+			body: "/opt/homebrew/bin/fish\n",
+			want: block,
+		},
+		{
+			name:     "file without a block",
+			existing: "/bin/bash\n/bin/zsh",
+			body:     "/opt/homebrew/bin/fish\n",
+			want:     "/bin/bash\n/bin/zsh\n\n" + block,
+		},
+		{
+			name:     "replaces an existing block",
+			existing: "/bin/bash\n# >>> henrik-os fish >>>\n/usr/local/bin/fish\n/old\n# <<< henrik-os fish <<<\n/bin/zsh\n",
+			body:     "/opt/homebrew/bin/fish",
+			want:     "/bin/bash\n" + block + "/bin/zsh\n",
+		},
+		{
+			name:     "leaves other blocks alone",
+			existing: "# >>> henrik-os ssh >>>\nHost *\n# <<< henrik-os ssh <<<\n",
+			body:     "/opt/homebrew/bin/fish\n",
+			want:     "# >>> henrik-os ssh >>>\nHost *\n# <<< henrik-os ssh <<<\n\n" + block,
+		},
+		{
+			name:     "empty body removes the block",
+			existing: "/bin/bash\n" + block + "/bin/zsh\n",
+			want:     "/bin/bash\n/bin/zsh\n",
+		},
+		{
+			name:     "empty body without a block",
+			existing: "/bin/bash\n",
+			want:     "/bin/bash\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderBlock([]byte(tt.existing), "fish", tt.body)
+			if string(got) != tt.want {
+				t.Errorf("RenderBlock =\n%q\nwant\n%q", got, tt.want)
+			}
+			again := RenderBlock(got, "fish", tt.body)
+			if string(again) != string(got) {
+				t.Errorf("RenderBlock is not idempotent:\n%q\nthen\n%q", got, again)
+			}
+			if body, _ := ExtractBlock(got, "fish"); tt.body != "" && body != "/opt/homebrew/bin/fish\n" {
+				t.Errorf("ExtractBlock = %q", body)
+			}
+		})
+	}
+}
+
+func TestWriteBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shells")
+	if err := os.WriteFile(path, []byte("/bin/bash\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := WriteBlock(io.Discard, path, "fish", "/opt/homebrew/bin/fish\n", 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if want := "/bin/bash\n\n# >>> henrik-os fish >>>\n/opt/homebrew/bin/fish\n# <<< henrik-os fish <<<\n"; string(data) != want {
+		t.Errorf("file holds %q, want %q", data, want)
+	}
+	if bak, _ := os.ReadFile(path + ".bak"); string(bak) != "/bin/bash\n" {
+		t.Errorf("backup holds %q; an unchanged block must not be written again", bak)
+	}
+
+	// A file holding only the body is adopted rather than duplicated.
+	adopted := filepath.Join(t.TempDir(), "config.fish")
+	if err := os.WriteFile(adopted, []byte("set -g fish_greeting\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteBlock(io.Discard, adopted, "fish", "set -g fish_greeting\n", 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(adopted); string(data) != "# >>> henrik-os fish >>>\nset -g fish_greeting\n# <<< henrik-os fish <<<\n" {
+		t.Errorf("adopted file holds %q", data)
+	}
+}
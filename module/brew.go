@@ -0,0 +1,115 @@
+package module
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// Brew is the Homebrew package manager. Cask selects casks instead of
+// formulae.
+type Brew struct {
+	Path string
+	Cask bool
+}
+
+func (b *Brew) Name() string { return "brew" }
+
+func (b *Brew) command(args ...string) *exec.Cmd {
+	if b.Cask {
+		args = append([]string{args[0], "--cask"}, args[1:]...)
+	}
+	path := b.Path
+	if path == "" {
+		path = "brew"
+	}
+	return exec.Command(path, args...)
+}
+
+func (b *Brew) run(w io.Writer, args ...string) error {
+	cmd := b.command(args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return cmd.Run()
+}
+
+func (b *Brew) IsInstalled(pkg string) bool {
+	out, err := b.command("list", pkg).CombinedOutput()
+	if err != nil {
+		return false
+	}
+	return len(strings.TrimSpace(string(out))) > 0
+}
+
+// Inventory returns every installed formula (or cask) mapped to its
+// installed version, using a single `brew list --versions` call.
+func (b *Brew) Inventory() (map[string]string, error) {
+	args := []string{"list", "--versions"}
+	if !b.Cask {
+		args = append(args, "--formula")
+	}
+	out, err := b.command(args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	inventory := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		inventory[fields[0]] = fields[len(fields)-1]
+	}
+	return inventory, nil
+}
+
+// inInventory reports whether pkg is installed. Tap-qualified names
+// ("user/tap/pkg") are listed by brew under their short name.
+func inInventory(inventory map[string]string, pkg string) bool {
+	_, ok := inventory[path.Base(pkg)]
+	return ok
+}
+
+func (b *Brew) Install(w io.Writer, pkgs ...string) error {
+	return b.run(w, append([]string{"install"}, pkgs...)...)
+}
+
+func (b *Brew) Upgrade(w io.Writer, pkgs ...string) error {
+	return b.run(w, append([]string{"upgrade"}, pkgs...)...)
+}
+
+func (b *Brew) Remove(w io.Writer, pkgs ...string) error {
+	return b.run(w, append([]string{"uninstall"}, pkgs...)...)
+}
+
+func (b *Brew) List() ([]string, error) {
+	args := []string{"list", "-1"}
+	if !b.Cask {
+		args = append(args, "--formula")
+	}
+	out, err := b.command(args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// brewPath returns the brew binary, also checking the default prefixes so a
+// Homebrew installed earlier in the same run is found before PATH is updated.
+func brewPath() string {
+	if p, err := exec.LookPath("brew"); err == nil {
+		return p
+	}
+	for _, p := range []string{
+		"/opt/homebrew/bin/brew",
+		"/usr/local/bin/brew",
+		"/home/linuxbrew/.linuxbrew/bin/brew",
+	} {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package module
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Brewfile is the package set of a Homebrew Bundle file.
+type Brewfile struct {
+	Taps  []string
+	Brews []string
+	Casks []string
+	Mas   []MasApp
+}
+
+// MasApp is a Mac App Store entry installed with mas.
+type MasApp struct {
+	Name string
+	ID   int
+}
+
+// ReadBrewfile parses the Brewfile at path.
+func ReadBrewfile(path string) (*Brewfile, error) {
+	f, err := os.Open(ExpandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading Brewfile: %w", err)
+	}
+	defer f.Close()
+
+	bf, err := ParseBrewfile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bf, nil
+}
+
+// ParseBrewfile parses tap, brew, cask and mas entries. Other entry types
+// (cask_args, vscode, whalebrew, ...) and per-entry options are ignored.
+func ParseBrewfile(r io.Reader) (*Brewfile, error) {
+	bf := &Brewfile{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kind, rest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "tap", "brew", "cask", "mas":
+		default:
+			continue
+		}
+		name, opts, err := parseQuoted(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		switch kind {
+		case "tap":
+			bf.Taps = appendUnique(bf.Taps, name)
+		case "brew":
+			bf.Brews = appendUnique(bf.Brews, name)
+		case "cask":
+			bf.Casks = appendUnique(bf.Casks, name)
+		case "mas":
+			id, err := masID(opts)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			bf.addMas(MasApp{Name: name, ID: id})
+		}
+	}
+	return bf, scanner.Err()
+}
+
+// parseQuoted splits `"name", opts...` into the unquoted name and the
+// remaining options.
+func parseQuoted(s string) (string, string, error) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') {
+		return "", "", fmt.Errorf("expected quoted name in %q", s)
+	}
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated string in %q", s)
+	}
+	name := s[1 : end+1]
+	rest := strings.TrimSpace(s[end+2:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, ","))
+	return name, rest, nil
+}
+
+func masID(opts string) (int, error) {
+	for _, opt := range strings.Split(opts, ",") {
+		key, value, ok := strings.Cut(opt, ":")
+		if ok && strings.TrimSpace(key) == "id" {
+			return strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	return 0, fmt.Errorf("mas entry without id")
+}
+
+// Merge adds every entry of other that is not already present.
+func (b *Brewfile) Merge(other *Brewfile) {
+	for _, t := range other.Taps {
+		b.Taps = appendUnique(b.Taps, t)
+	}
+	for _, p := range other.Brews {
+		b.Brews = appendUnique(b.Brews, p)
+	}
+	for _, c := range other.Casks {
+		b.Casks = appendUnique(b.Casks, c)
+	}
+	for _, app := range other.Mas {
+		b.addMas(app)
+	}
+}
+
+func (b *Brewfile) addMas(app MasApp) {
+	for _, existing := range b.Mas {
+		if existing.ID == app.ID {
+			return
+		}
+	}
+	b.Mas = append(b.Mas, app)
+}
+
+// WriteTo writes the Brewfile in `brew bundle dump` format.
+func (b *Brewfile) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	for _, t := range b.Taps {
+		fmt.Fprintf(&sb, "tap %q\n", t)
+	}
+	for _, p := range b.Brews {
+		fmt.Fprintf(&sb, "brew %q\n", p)
+	}
+	for _, c := range b.Casks {
+		fmt.Fprintf(&sb, "cask %q\n", c)
+	}
+	for _, app := range b.Mas {
+		fmt.Fprintf(&sb, "mas %q, id: %d\n", app.Name, app.ID)
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, existing := range list {
+		if existing == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
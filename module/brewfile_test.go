@@ -0,0 +1,75 @@
+package module
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBrewfile(t *testing.T) {
+	bf, err := ParseBrewfile(strings.NewReader(`# Taps
+tap "homebrew/bundle"
+tap "user/tools", "https://example.com/tools.git"
+cask_args appdir: "~/Applications"
+
+brew "git"
+brew "fish", restart_service: :changed # shell
+brew 'ripgrep'
+brew "git"
+  brew "neovim", args: ["HEAD"]
+cask "ghostty", greedy: true
+vscode "golang.go"
+mas "Xcode", id: 497799835
+mas "Things 3", id:904280696
+mas "Xcode copy", id: 497799835
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Brewfile{
+		Taps:  []string{"homebrew/bundle", "user/tools"},
+		Brews: []string{"git", "fish", "ripgrep", "neovim"},
+		Casks: []string{"ghostty"},
+		Mas:   []MasApp{{Name: "Xcode", ID: 497799835}, {Name: "Things 3", ID: 904280696}},
+	}
+	if !reflect.DeepEqual(bf, want) {
+		t.Errorf("ParseBrewfile =\n%+v\nwant\n%+v", bf, want)
+	}
+}
+
+func TestParseBrewfileErrors(t *testing.T) {
+	tests := []struct{ input, err string }{
+		{"brew git", "line 1: expected quoted name"},
+		{"\ncask \"ghostty", "line 2: unterminated string"},
+		{`mas "Xcode"`, "line 1: mas entry without id"},
+		{`mas "Xcode", id: abc`, "line 1:"},
+	}
+	for _, tt := range tests {
+		_, err := ParseBrewfile(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseBrewfile(%q) = %v, want %q", tt.input, err, tt.err)
+		}
+	}
+}
+
+func TestBrewfileRoundTrip(t *testing.T) {
+	bf := &Brewfile{
+		Taps:  []string{"homebrew/bundle"},
+		Brews: []string{"git"},
+		Casks: []string{"ghostty"},
+		Mas:   []MasApp{{Name: "Xcode", ID: 497799835}},
+	}
+	bf.Merge(&Brewfile{Brews: []string{"git", "fish"}, Mas: []MasApp{{Name: "Xcode", ID: 497799835}}})
+
+	var sb strings.Builder
+	if _, err := bf.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseBrewfile(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, bf) {
+		t.Errorf("round trip of\n%s= %+v, want %+v", sb.String(), got, bf)
+	}
+}
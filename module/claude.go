@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"strings"
 )
 
 func init() {
@@ -15,7 +16,32 @@ type Claude struct{}
 func (c *Claude) Name() string         { return "Claude Code" }
 func (c *Claude) ID() string           { return "claude" }
 func (c *Claude) Description() string  { return "Install Claude Code CLI" }
-func (c *Claude) Dependencies() []string { return []string{"node"} }
+func (c *Claude) Dependencies() []string { return nil }
+func (c *Claude) SoftDependencies() []string { return []string{"node"} }
+func (c *Claude) Tags() []string         { return []string{"ai"} }
+func (c *Claude) Platforms() []string    { return []string{"darwin"} }
+
+func (c *Claude) Commands() []string {
+	return []string{"npm install -g @anthropic-ai/claude-code (if missing)"}
+}
+
+// Check reports whether the claude CLI is on PATH.
+func (c *Claude) Check() error {
+	_, err := exec.LookPath("claude")
+	return err
+}
+
+// Preflight requires npm unless Claude Code is already installed or node is
+// part of this run.
+func (c *Claude) Preflight() []Result {
+	if _, err := exec.LookPath("claude"); err == nil || Present("node") {
+		return nil
+	}
+	return []Result{
+		needBinary("npm", "select the node module or install Node.js"),
+		needHost("https://registry.npmjs.org"),
+	}
+}
 
 func (c *Claude) Install(w io.Writer) error {
 	if _, err := exec.LookPath("claude"); err == nil {
@@ -23,6 +49,10 @@ func (c *Claude) Install(w io.Writer) error {
 		return nil
 	}
 
+	if _, err := exec.LookPath("npm"); err != nil {
+		return fmt.Errorf("npm not found: select the node module or install Claude Code another way")
+	}
+
 	fmt.Fprintln(w, "  Installing Claude Code...")
 	cmd := exec.Command("npm", "install", "-g", "@anthropic-ai/claude-code")
 	cmd.Stdout = w
@@ -33,3 +63,38 @@ func (c *Claude) Install(w io.Writer) error {
 	fmt.Fprintln(w, "  Claude Code installed")
 	return nil
 }
+
+// versions returns the installed and latest published Claude Code versions.
+func (c *Claude) versions() (string, string, error) {
+	out, err := exec.Command("claude", "--version").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("claude --version: %w", err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", "", fmt.Errorf("claude --version returned nothing")
+	}
+	latest, err := exec.Command("npm", "view", "@anthropic-ai/claude-code", "version").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("npm view: %w", err)
+	}
+	return fields[0], strings.TrimSpace(string(latest)), nil
+}
+
+func (c *Claude) PlanUpdate() ([]string, error) {
+	current, latest, err := c.versions()
+	if err != nil || current == latest {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("Claude Code %s → %s", current, latest)}, nil
+}
+
+func (c *Claude) Update(w io.Writer) error {
+	cmd := exec.Command("claude", "update")
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("updating Claude Code: %w", err)
+	}
+	return nil
+}
@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"strings"
 )
 
 func init() {
@@ -18,8 +17,9 @@ func (c *ClaudeConfig) Name() string         { return "Claude Code Config" }
 func (c *ClaudeConfig) ID() string           { return "claude-config" }
 func (c *ClaudeConfig) Description() string  { return "Sync Claude Code config (CLAUDE.md, settings, hooks, MCP, statusline)" }
 func (c *ClaudeConfig) Dependencies() []string { return nil }
+func (c *ClaudeConfig) Tags() []string         { return []string{"ai"} }
 
-func (c *ClaudeConfig) Install(w io.Writer) error {
+func (c *ClaudeConfig) Files() []File {
 	claudeDir := filepath.Join(HomeDir(), ".claude")
 
 	// Config files to sync
@@ -31,11 +31,9 @@ func (c *ClaudeConfig) Install(w io.Writer) error {
 		{"claude/statusline-fish.fish", "statusline-fish.fish"},
 	}
 
+	var result []File
 	for _, f := range files {
-		dest := filepath.Join(claudeDir, f.rel)
-		if err := WriteEmbedded(w, &configs.FS, f.src, dest, 0o644); err != nil {
-			return err
-		}
+		result = append(result, File{Src: f.src, Dest: filepath.Join(claudeDir, f.rel), Perm: 0o644})
 	}
 
 	// Hooks (need executable permission)
@@ -46,11 +44,35 @@ func (c *ClaudeConfig) Install(w io.Writer) error {
 	}
 
 	for _, h := range hooks {
-		dest := filepath.Join(claudeDir, "hooks", h)
-		if err := WriteEmbedded(w, &configs.FS, "claude/hooks/"+h, dest, 0o755); err != nil {
-			return err
+		result = append(result, File{Src: "claude/hooks/" + h, Dest: filepath.Join(claudeDir, "hooks", h), Perm: 0o755})
+	}
+	return result
+}
+
+func (c *ClaudeConfig) Steps() []Step {
+	return []Step{{
+		Title:  "Anthropic API",
+		Action: `set -Ux ANTHROPIC_API_KEY "sk-..."`,
+		Done:   envSet("ANTHROPIC_API_KEY"),
+	}}
+}
+
+// Verify parses the JSON configs.
+func (c *ClaudeConfig) Verify() error {
+	for _, f := range c.Files() {
+		if strings.HasSuffix(f.Dest, ".json") {
+			if err := verifyJSON(f.Dest, false); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
+
+func (c *ClaudeConfig) Install(w io.Writer) error {
+	if err := InstallFiles(w, c); err != nil {
+		return err
+	}
 
 	fmt.Fprintln(w, "  Claude Code config synced")
 	return nil
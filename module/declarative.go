@@ -0,0 +1,289 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Declarative is a module described by a TOML or YAML file instead of Go
+// code:
+//
+//	id = "tmux"
+//	name = "tmux"
+//	description = "Configure tmux"
+//	dependencies = ["homebrew"]
+//	soft_dependencies = ["fish"]
+//	conflicts = ["screen"]
+//	platforms = ["darwin", "linux"]
+//	tags = ["terminal"]
+//	packages = ["tmux"]
+//	requires = ["curl"]
+//	check = ["test -f ~/.tmux.conf"]
+//	install = ["tmux source-file ~/.tmux.conf || true"]
+//
+//	[[files]]
+//	src = "tmux.conf"      # relative to the module file
+//	dest = "~/.tmux.conf"
+//	mode = "0644"
+//
+// The same keys can be written in YAML, in a .yaml or .yml file:
+//
+//	id: tmux
+//	packages: [tmux]
+//	files:
+//	  - src: tmux.conf
+//	    dest: ~/.tmux.conf
+//	    mode: "0644"
+//
+// Files are always deployed. Install commands run through sh, and are
+// skipped when every check command succeeds.
+type Declarative struct {
+	id          string
+	name        string
+	description string
+	deps        []string
+	softDeps    []string
+	conflicts   []string
+	platforms   []string
+	tags        []string
+	packages    []string
+	requires    []string
+	files       []File
+	install     []string
+	check       []string
+	path        string
+}
+
+func (d *Declarative) Name() string               { return d.name }
+func (d *Declarative) ID() string                 { return d.id }
+func (d *Declarative) Description() string        { return d.description }
+func (d *Declarative) Dependencies() []string     { return d.deps }
+func (d *Declarative) Platforms() []string        { return d.platforms }
+func (d *Declarative) Conflicts() []string        { return d.conflicts }
+func (d *Declarative) SoftDependencies() []string { return d.softDeps }
+func (d *Declarative) Tags() []string             { return d.tags }
+func (d *Declarative) Packages() []string         { return d.packages }
+func (d *Declarative) Files() []File              { return d.files }
+
+func (d *Declarative) Commands() []string { return d.install }
+
+// Path returns the file the module was loaded from.
+func (d *Declarative) Path() string { return d.path }
+
+// Preflight requires every binary listed in requires to be on PATH.
+func (d *Declarative) Preflight() []Result {
+	var results []Result
+	for _, bin := range d.requires {
+		results = append(results, needBinary(bin, "install "+bin+" first"))
+	}
+	return results
+}
+
+// Verify runs the check commands after install, if there are any.
+func (d *Declarative) Verify() error {
+	if len(d.check) == 0 {
+		return nil
+	}
+	return d.Check()
+}
+
+// Check runs the check commands and returns the first failure. A module
+// without check commands returns ErrNoCheck: whether it is installed is
+// unknown.
+func (d *Declarative) Check() error {
+	if len(d.check) == 0 {
+		return ErrNoCheck
+	}
+	for _, c := range d.check {
+		cmd := exec.Command("sh", "-c", c)
+		cmd.Dir = filepath.Dir(d.path)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("check %q failed: %w", c, err)
+		}
+	}
+	return nil
+}
+
+func (d *Declarative) Install(w io.Writer) error {
+	if err := EnsurePackages(w, d.packages...); err != nil {
+		return err
+	}
+	if err := InstallFiles(w, d); err != nil {
+		return err
+	}
+
+	if len(d.install) > 0 && d.Check() == nil {
+		fmt.Fprintf(w, "  %s already installed\n", d.name)
+		return nil
+	}
+	for _, c := range d.install {
+		fmt.Fprintf(w, "  $ %s\n", c)
+		cmd := exec.Command("sh", "-c", c)
+		cmd.Dir = filepath.Dir(d.path)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("running %q: %w", c, err)
+		}
+	}
+	fmt.Fprintf(w, "  %s configured\n", d.name)
+	return nil
+}
+
+// LoadDeclarative registers every *.toml, *.yaml and *.yml module in dir. A
+// missing directory is not an error; invalid files are skipped and reported.
+func LoadDeclarative(dir string) []error {
+	var paths []string
+	for _, ext := range []string{".toml", ".yaml", ".yml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+ext))
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	for _, path := range paths {
+		m, err := ReadDeclarative(path)
+		if err == nil && ByID(m.ID()) != nil {
+			err = fmt.Errorf("module %s is already registered", m.ID())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		Register(m)
+	}
+	return errs
+}
+
+// ReadDeclarative parses a declarative module file, as YAML when it ends in
+// .yaml or .yml and as TOML otherwise.
+func ReadDeclarative(path string) (*Declarative, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decode := decodeTOML
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		decode = decodeYAML
+	}
+	doc, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Declarative{path: path}
+	var errs []string
+	str := func(key string) string {
+		v, ok := doc[key]
+		if !ok {
+			return ""
+		}
+		s, ok := v.(string)
+		if !ok {
+			errs = append(errs, key+" must be a string")
+		}
+		return s
+	}
+	list := func(key string) []string {
+		l, err := stringList(doc[key])
+		if err != nil {
+			errs = append(errs, key+" "+err.Error())
+		}
+		return l
+	}
+
+	d.id = str("id")
+	d.name = str("name")
+	d.description = str("description")
+	d.deps = list("dependencies")
+	d.softDeps = list("soft_dependencies")
+	d.conflicts = list("conflicts")
+	d.platforms = list("platforms")
+	d.tags = list("tags")
+	d.packages = list("packages")
+	d.requires = list("requires")
+	d.install = list("install")
+	d.check = list("check")
+
+	if d.id == "" {
+		errs = append(errs, "id is required")
+	} else if err := ValidateID(d.id); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if d.name == "" {
+		d.name = d.id
+	}
+
+	for i, entry := range tables(doc["files"]) {
+		f, err := declarativeFile(entry, filepath.Dir(path))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("files[%d]: %v", i, err))
+			continue
+		}
+		d.files = append(d.files, f)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return d, nil
+}
+
+func declarativeFile(entry any, dir string) (File, error) {
+	t, ok := entry.(map[string]any)
+	if !ok {
+		return File{}, fmt.Errorf("must be a table")
+	}
+	src, _ := t["src"].(string)
+	dest, _ := t["dest"].(string)
+	if src == "" || dest == "" {
+		return File{}, fmt.Errorf("src and dest are required")
+	}
+
+	// Modes are octal strings: a TOML integer such as 644 is decimal, and
+	// 0o644 cannot be told apart from 420 once decoded.
+	perm := os.FileMode(0o644)
+	switch mode := t["mode"].(type) {
+	case nil:
+	case string:
+		n, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || n > 0o777 {
+			return File{}, fmt.Errorf("invalid mode %q", mode)
+		}
+		perm = os.FileMode(n)
+	default:
+		return File{}, fmt.Errorf(`mode must be an octal string such as "0644"`)
+	}
+
+	return File{
+		Src:  filepath.ToSlash(src),
+		Dest: ExpandHome(dest),
+		Perm: perm,
+		FS:   sourceFS{os.DirFS(dir)},
+	}, nil
+}
+
+func stringList(v any) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("must be a list of strings")
+	}
+	var result []string
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a list of strings")
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
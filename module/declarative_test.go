@@ -0,0 +1,210 @@
+package module
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDeclarative writes a module file into a new directory and returns
+// its path.
+func writeDeclarative(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mod.toml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadDeclarative(t *testing.T) {
+	path := writeDeclarative(t, `
+id = "tmux"
+description = "Configure tmux"
+dependencies = ["homebrew"]
+tags = ["terminal"]
+check = ["true"]
+
+[[files]]
+src = "tmux.conf"
+dest = "/tmp/tmux.conf"
+mode = "0600"
+`)
+	d, err := ReadDeclarative(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID() != "tmux" || d.Name() != "tmux" || Category(d) != "terminal" {
+		t.Errorf("ReadDeclarative = %+v", d)
+	}
+	if len(d.Files()) != 1 || d.Files()[0].Perm != 0o600 || d.Files()[0].Dest != "/tmp/tmux.conf" {
+		t.Errorf("files = %+v", d.Files())
+	}
+}
+
+func TestReadDeclarativeErrors(t *testing.T) {
+	tests := []struct {
+		name, body, err string
+	}{
+		{"missing id", `name = "tmux"`, "id is required"},
+		{"id not a string", `id = 1`, "id must be a string"},
+		{"non-string list entry", "id = \"tmux\"\ninstall = [\"true\", 1]", "install must be a list of strings"},
+		{"list not a list", "id = \"tmux\"\ntags = \"terminal\"", "tags must be a list of strings"},
+		{"integer mode", "id = \"tmux\"\n[[files]]\nsrc = \"a\"\ndest = \"/tmp/a\"\nmode = 644", "files[0]: mode must be an octal string"},
+		{"invalid mode", "id = \"tmux\"\n[[files]]\nsrc = \"a\"\ndest = \"/tmp/a\"\nmode = \"0999\"", `files[0]: invalid mode "0999"`},
+		{"missing src", "id = \"tmux\"\n[[files]]\ndest = \"/tmp/a\"", "files[0]: src and dest are required"},
+		{"missing dest", "id = \"tmux\"\n[[files]]\nsrc = \"a\"", "files[0]: src and dest are required"},
+		{"invalid TOML", `id = "tmux`, ""},
+		{"reserved id", `id = "all"`, `id "all" is reserved`},
+		{"id with comma", `id = "a,b"`, `invalid id "a,b"`},
+		{"id with space", `id = "my mod"`, `invalid id "my mod"`},
+		{"tag selector id", `id = "tag:editor"`, `invalid id "tag:editor"`},
+		{"glob id", `id = "tmux*"`, `invalid id "tmux*"`},
+		{"exclusion id", `id = "-tmux"`, `invalid id "-tmux"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadDeclarative(writeDeclarative(t, tt.body))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ReadDeclarative = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadDeclarativeYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tmux.yaml")
+	err := os.WriteFile(path, []byte(`
+id: tmux
+dependencies: [homebrew]
+tags: [terminal]
+files:
+  - src: tmux.conf
+    dest: /tmp/tmux.conf
+    mode: "0600"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadDeclarative(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID() != "tmux" || Category(d) != "terminal" || len(d.Dependencies()) != 1 {
+		t.Errorf("ReadDeclarative = %+v", d)
+	}
+	if len(d.Files()) != 1 || d.Files()[0].Perm != 0o600 || d.Files()[0].Dest != "/tmp/tmux.conf" {
+		t.Errorf("files = %+v", d.Files())
+	}
+
+	// The same checks apply as for TOML.
+	if err := os.WriteFile(path, []byte("id: tmux\nfiles:\n  - src: a\n    dest: /tmp/a\n    mode: 0644\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadDeclarative(path); err == nil || !strings.Contains(err.Error(), "mode must be an octal string") {
+		t.Errorf("ReadDeclarative with an integer mode = %v", err)
+	}
+}
+
+func TestDeclarativeWithoutChecks(t *testing.T) {
+	d, err := ReadDeclarative(writeDeclarative(t, `id = "tmux"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Check(); !errors.Is(err, ErrNoCheck) {
+		t.Errorf("Check = %v, want ErrNoCheck", err)
+	}
+	if err := d.Verify(); err != nil {
+		t.Errorf("Verify = %v", err)
+	}
+}
+
+func TestDeclarativeInstall(t *testing.T) {
+	tests := []struct {
+		name  string
+		check string
+		ran   bool
+	}{
+		{"all checks pass", `check = ["true", "test -f tmux.conf"]`, false},
+		{"a check fails", `check = ["true", "false"]`, true},
+		{"no checks", ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeDeclarative(t, `
+id = "tmux"
+install = ["touch installed"]
+`+tt.check+`
+
+[[files]]
+src = "tmux.conf"
+dest = "deployed/tmux.conf"
+`)
+			dir := filepath.Dir(path)
+			if err := os.WriteFile(filepath.Join(dir, "tmux.conf"), []byte("set -g mouse on\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			d, err := ReadDeclarative(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.files[0].Dest = filepath.Join(dir, "deployed", "tmux.conf")
+
+			if err := d.Install(io.Discard); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(d.files[0].Dest); err != nil {
+				t.Errorf("files were not deployed: %v", err)
+			}
+			_, err = os.Stat(filepath.Join(dir, "installed"))
+			if ran := err == nil; ran != tt.ran {
+				t.Errorf("install commands ran = %v, want %v", ran, tt.ran)
+			}
+		})
+	}
+}
+
+func TestDeclarativeInstallFailure(t *testing.T) {
+	d, err := ReadDeclarative(writeDeclarative(t, `
+id = "tmux"
+install = ["exit 3", "touch never"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Install(io.Discard); err == nil || !strings.Contains(err.Error(), `running "exit 3"`) {
+		t.Errorf("Install = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(d.Path()), "never")); err == nil {
+		t.Error("Install kept going after a failing command")
+	}
+}
+
+func TestLoadDeclarativeDuplicate(t *testing.T) {
+	useRegistry(t, &testModule{id: "fish"})
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"fish.toml": `id = "fish"`,
+		"tmux.toml": `id = "tmux"`,
+		"bad.toml":  `name = "nameless"`,
+		"nvim.yml":  "id: nvim\n",
+		"notes.txt": `id = "ignored"`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errs := LoadDeclarative(dir)
+	if len(errs) != 2 {
+		t.Errorf("LoadDeclarative errors = %v, want two", errs)
+	}
+	if ByID("tmux") == nil || ByID("nvim") == nil {
+		t.Error("valid module was not registered")
+	}
+	if _, ok := ByID("fish").(*testModule); !ok {
+		t.Error("duplicate id replaced the registered module")
+	}
+}
@@ -0,0 +1,163 @@
+package module
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SoftDependent is implemented by modules that work better alongside other
+// modules without requiring them. Soft dependencies are never pulled in by
+// Resolve, but when selected they are installed first.
+type SoftDependent interface {
+	SoftDependencies() []string
+}
+
+// Conflicter is implemented by modules that cannot be installed together
+// with some other modules.
+type Conflicter interface {
+	Conflicts() []string
+}
+
+// installing holds the IDs of the modules in the current install.
+var installing = map[string]bool{}
+
+// SetInstalling records the modules of the current install, which Present
+// reports as present. Installers call it with the resolved modules before
+// preflight and again with the final list once modules were skipped.
+func SetInstalling(modules []Module) {
+	installing = make(map[string]bool, len(modules))
+	for _, m := range modules {
+		installing[m.ID()] = true
+	}
+}
+
+// Present reports whether the module with the given ID is part of the
+// current install or was installed by henrik-os before, which is judged by
+// its files being deployed. A passing Check is not enough: a fish binary
+// from the distribution does not mean the fish module is in use. Modules
+// use it to adapt to soft dependencies at install time.
+func Present(id string) bool {
+	if installing[id] {
+		return true
+	}
+	fp, ok := ByID(id).(FileProvider)
+	if !ok {
+		return false
+	}
+	for _, f := range fp.Files() {
+		switch state, _ := Status(f); state {
+		case StateCopy, StateLinked, StateBlock, StateStaleBlock, StateMerged:
+			return true
+		}
+	}
+	return false
+}
+
+// checkRelations reports soft dependencies and conflicts of m that name
+// modules which do not exist, like Resolve does for hard dependencies.
+func checkRelations(m Module) error {
+	for _, id := range softDeps(m) {
+		if ByID(id) == nil {
+			return fmt.Errorf("module %s: unknown soft dependency: %s", m.ID(), id)
+		}
+	}
+	if c, ok := m.(Conflicter); ok {
+		for _, id := range c.Conflicts() {
+			if ByID(id) == nil {
+				return fmt.Errorf("module %s: unknown conflict: %s", m.ID(), id)
+			}
+		}
+	}
+	return nil
+}
+
+func softDeps(m Module) []string {
+	if s, ok := m.(SoftDependent); ok {
+		return s.SoftDependencies()
+	}
+	return nil
+}
+
+func checkConflicts(needed map[string]bool) error {
+	for _, m := range registry {
+		c, ok := m.(Conflicter)
+		if !ok || !needed[m.ID()] {
+			continue
+		}
+		for _, id := range c.Conflicts() {
+			if needed[id] {
+				return fmt.Errorf("module %s conflicts with %s", m.ID(), id)
+			}
+		}
+	}
+	return nil
+}
+
+// installOrder sorts the needed modules so every hard and selected soft
+// dependency comes before its dependents. Ties keep registration order.
+func installOrder(needed map[string]bool) ([]Module, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var result []Module
+	var stack []string
+
+	var visit func(m Module) error
+	visit = func(m Module) error {
+		switch state[m.ID()] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s → %s", strings.Join(stack, " → "), m.ID())
+		}
+		state[m.ID()] = visiting
+		stack = append(stack, m.ID())
+		for _, id := range slices.Concat(m.Dependencies(), softDeps(m)) {
+			if !needed[id] {
+				continue
+			}
+			if err := visit(ByID(id)); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[m.ID()] = done
+		result = append(result, m)
+		return nil
+	}
+
+	for _, m := range registry {
+		if needed[m.ID()] {
+			if err := visit(m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// Exclude returns modules without the given IDs and without any module
+// that depends on them, directly or transitively.
+func Exclude(modules []Module, ids []string) []Module {
+	dropped := make(map[string]bool)
+	for _, id := range ids {
+		dropped[id] = true
+	}
+	var result []Module
+	// modules is in install order, so dependencies are seen first.
+	for _, m := range modules {
+		drop := dropped[m.ID()]
+		for _, dep := range m.Dependencies() {
+			drop = drop || dropped[dep]
+		}
+		if drop {
+			dropped[m.ID()] = true
+			continue
+		}
+		result = append(result, m)
+	}
+	return result
+}
@@ -0,0 +1,197 @@
+package module
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testModule is a module with the given relations that installs nothing.
+type testModule struct {
+	id        string
+	deps      []string
+	soft      []string
+	conflicts []string
+	platforms []string
+	tags      []string
+}
+
+func (m *testModule) Name() string               { return m.id }
+func (m *testModule) ID() string                 { return m.id }
+func (m *testModule) Description() string        { return "" }
+func (m *testModule) Dependencies() []string     { return m.deps }
+func (m *testModule) SoftDependencies() []string { return m.soft }
+func (m *testModule) Conflicts() []string        { return m.conflicts }
+func (m *testModule) Platforms() []string        { return m.platforms }
+func (m *testModule) Tags() []string             { return m.tags }
+func (m *testModule) Install(w io.Writer) error  { return nil }
+
+// useRegistry replaces the registered modules for the duration of a test.
+func useRegistry(t *testing.T, modules ...Module) {
+	t.Helper()
+	prev, prevInstalling := registry, installing
+	registry = modules
+	t.Cleanup(func() { registry, installing = prev, prevInstalling })
+}
+
+func ids(modules []Module) []string {
+	var out []string
+	for _, m := range modules {
+		out = append(out, m.ID())
+	}
+	return out
+}
+
+func TestResolve(t *testing.T) {
+	useRegistry(t,
+		&testModule{id: "base"},
+		&testModule{id: "late", soft: []string{"shell"}},
+		&testModule{id: "shell", deps: []string{"base"}},
+		&testModule{id: "alt", conflicts: []string{"base"}},
+		&testModule{id: "loop1", deps: []string{"loop2"}},
+		&testModule{id: "loop2", deps: []string{"loop1"}},
+		&testModule{id: "soft1", soft: []string{"soft2"}},
+		&testModule{id: "soft2", soft: []string{"soft1"}},
+		&testModule{id: "badsoft", soft: []string{"missing"}},
+		&testModule{id: "badconflict", conflicts: []string{"missing"}},
+		&testModule{id: "baddep", deps: []string{"missing"}},
+		&testModule{id: "elsewhere", platforms: []string{"plan9"}},
+	)
+
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+		err  string
+	}{
+		{name: "dependencies first", ids: []string{"shell"}, want: []string{"base", "shell"}},
+		{name: "soft dependency not pulled in", ids: []string{"late"}, want: []string{"late"}},
+		{name: "selected soft dependency first", ids: []string{"late", "shell"}, want: []string{"base", "shell", "late"}},
+		{name: "registration order", ids: []string{"shell", "base"}, want: []string{"base", "shell"}},
+		{name: "conflict", ids: []string{"alt", "shell"}, err: "module alt conflicts with base"},
+		{name: "no conflict alone", ids: []string{"alt"}, want: []string{"alt"}},
+		{name: "hard cycle", ids: []string{"loop1"}, err: "dependency cycle: loop1 → loop2 → loop1"},
+		{name: "soft cycle", ids: []string{"soft1", "soft2"}, err: "dependency cycle"},
+		{name: "soft cycle half selected", ids: []string{"soft1"}, want: []string{"soft1"}},
+		{name: "unknown module", ids: []string{"nope"}, err: "unknown module: nope"},
+		{name: "unknown dependency", ids: []string{"baddep"}, err: "unknown module: missing"},
+		{name: "unknown soft dependency", ids: []string{"badsoft"}, err: "module badsoft: unknown soft dependency: missing"},
+		{name: "unknown conflict", ids: []string{"badconflict"}, err: "module badconflict: unknown conflict: missing"},
+		{name: "unsupported platform", ids: []string{"elsewhere"}, err: "not supported"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.ids)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Resolve(%v) error = %v, want %q", tt.ids, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("Resolve(%v) = %v, want %v", tt.ids, ids(got), tt.want)
+			}
+		})
+	}
+}
+
+// Ordering must not write soft dependencies into the spare capacity of the
+// slice a module returns from Dependencies.
+func TestResolveKeepsDependencies(t *testing.T) {
+	deps := make([]string, 1, 4)
+	deps[0] = "base"
+	useRegistry(t,
+		&testModule{id: "base"},
+		&testModule{id: "extra"},
+		&testModule{id: "app", deps: deps, soft: []string{"extra"}},
+	)
+	if _, err := Resolve([]string{"app", "extra"}); err != nil {
+		t.Fatal(err)
+	}
+	if spare := deps[:2]; spare[1] != "" {
+		t.Errorf("Dependencies backing array overwritten: %q", spare)
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	useRegistry(t,
+		&testModule{id: "a", conflicts: []string{"b"}},
+		&testModule{id: "b"},
+		&testModule{id: "c"},
+	)
+	tests := []struct {
+		needed []string
+		err    bool
+	}{
+		{[]string{"a", "b"}, true},
+		{[]string{"b", "a"}, true},
+		{[]string{"a", "c"}, false},
+		{[]string{"b", "c"}, false},
+	}
+	for _, tt := range tests {
+		needed := make(map[string]bool)
+		for _, id := range tt.needed {
+			needed[id] = true
+		}
+		if err := checkConflicts(needed); (err != nil) != tt.err {
+			t.Errorf("checkConflicts(%v) = %v, want error %v", tt.needed, err, tt.err)
+		}
+	}
+}
+
+func TestExclude(t *testing.T) {
+	modules := []Module{
+		&testModule{id: "base"},
+		&testModule{id: "shell", deps: []string{"base"}},
+		&testModule{id: "prompt", deps: []string{"shell"}},
+		&testModule{id: "editor", soft: []string{"shell"}},
+		&testModule{id: "other"},
+	}
+	tests := []struct {
+		exclude []string
+		want    []string
+	}{
+		{nil, []string{"base", "shell", "prompt", "editor", "other"}},
+		{[]string{"other"}, []string{"base", "shell", "prompt", "editor"}},
+		{[]string{"prompt"}, []string{"base", "shell", "editor", "other"}},
+		{[]string{"shell"}, []string{"base", "editor", "other"}},
+		{[]string{"base"}, []string{"editor", "other"}},
+		{[]string{"missing"}, []string{"base", "shell", "prompt", "editor", "other"}},
+	}
+	for _, tt := range tests {
+		if got := ids(Exclude(modules, tt.exclude)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Exclude(%v) = %v, want %v", tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestPresent(t *testing.T) {
+	shell := &testModule{id: "shell"}
+	useRegistry(t, shell, &testModule{id: "prompt", soft: []string{"shell"}})
+
+	SetInstalling([]Module{shell})
+	if !Present("shell") {
+		t.Error("shell is part of the install but not present")
+	}
+
+	// Resolving, e.g. for info, leaves the current install alone.
+	if _, err := Resolve([]string{"prompt"}); err != nil {
+		t.Fatal(err)
+	}
+	if !Present("shell") {
+		t.Error("Resolve changed the modules of the current install")
+	}
+
+	// Skipped after preflight.
+	SetInstalling(Exclude([]Module{shell}, []string{"shell"}))
+	if Present("shell") {
+		t.Error("a skipped module is still present")
+	}
+	if Present("missing") {
+		t.Error("an unknown module is present")
+	}
+}
@@ -0,0 +1,170 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+func init() {
+	RegisterDiagnostic(Diagnostic{Name: "Homebrew", Run: checkBrew})
+	RegisterDiagnostic(Diagnostic{Name: "Login shell", Run: checkShell})
+	RegisterDiagnostic(Diagnostic{Name: "Xcode CLI tools", Run: checkXcode, Repair: repairXcode})
+	RegisterDiagnostic(Diagnostic{Name: "~/.ssh permissions", Run: checkSSHDir, Repair: repairSSHDir})
+	RegisterDiagnostic(Diagnostic{Name: "GitHub reachable", Run: checkGitHub})
+	RegisterDiagnostic(Diagnostic{Name: "Disk space", Run: checkDisk})
+	RegisterDiagnostic(Diagnostic{Name: "Config links", Run: checkLinks, Repair: repairLinks})
+}
+
+// githubURL is probed by the GitHub diagnostic.
+var githubURL = "https://github.com"
+
+func checkBrew() Result {
+	path := brewPath()
+	if path == "" {
+		if platform != "darwin" {
+			return passf("not used on %s", PlatformName(platform))
+		}
+		return Result{Severity: Fail, Message: "brew not found", Fix: "henrik-os install homebrew"}
+	}
+	prefix := filepath.Dir(filepath.Dir(path))
+	if _, err := exec.LookPath("brew"); err != nil {
+		return Result{
+			Severity: Warn,
+			Message:  fmt.Sprintf("brew installed in %s but not on PATH", prefix),
+			Fix:      fmt.Sprintf(`eval "$(%s shellenv)"`, path),
+		}
+	}
+	if platform == "darwin" && runtime.GOARCH == "arm64" && prefix == "/usr/local" {
+		return Result{
+			Severity: Warn,
+			Message:  "Intel Homebrew (/usr/local) on Apple Silicon",
+			Fix:      "install Homebrew under /opt/homebrew and migrate your packages",
+		}
+	}
+	return passf("%s (prefix %s)", path, prefix)
+}
+
+func checkShell() Result {
+	shell := os.Getenv("SHELL")
+	if filepath.Base(shell) == "fish" {
+		return passf("%s", shell)
+	}
+	if shell == "" {
+		shell = "unknown"
+	}
+	return Result{Severity: Warn, Message: "login shell is " + shell, Fix: "henrik-os install fish"}
+}
+
+func checkXcode() Result {
+	if platform != "darwin" {
+		return passf("not needed on %s", PlatformName(platform))
+	}
+	out, err := exec.Command("xcode-select", "-p").Output()
+	if err != nil {
+		return Result{Severity: Fail, Message: "not installed", Fix: "xcode-select --install"}
+	}
+	return passf("%s", strings.TrimSpace(string(out)))
+}
+
+// repairXcode only starts the installer. Waiting for it like Xcode.Install
+// would hang doctor when the dialog is dismissed.
+func repairXcode(w io.Writer) error {
+	if err := exec.Command("xcode-select", "--install").Run(); err != nil {
+		return fmt.Errorf("xcode-select --install: %w", err)
+	}
+	fmt.Fprintln(w, "  Started the Xcode CLI tools installer; run henrik-os doctor again once it has finished")
+	return nil
+}
+
+func sshDir() string {
+	return filepath.Join(HomeDir(), ".ssh")
+}
+
+func checkSSHDir() Result {
+	info, err := os.Stat(sshDir())
+	if err != nil {
+		return Result{Severity: Warn, Message: "~/.ssh does not exist", Fix: "henrik-os install ssh"}
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		return Result{Severity: Fail, Message: fmt.Sprintf("mode is %04o", perm), Fix: "chmod 700 ~/.ssh"}
+	}
+	return passf("0700")
+}
+
+func repairSSHDir(w io.Writer) error {
+	if err := os.MkdirAll(sshDir(), 0o700); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  chmod 700 %s\n", sshDir())
+	return os.Chmod(sshDir(), 0o700)
+}
+
+func checkGitHub() Result {
+	if err := reachable(githubURL); err != nil {
+		return Result{Severity: Fail, Message: err.Error(), Fix: "check your network connection, proxy or DNS"}
+	}
+	return passf("%s", githubURL)
+}
+
+func checkDisk() Result {
+	gb, err := freeGB()
+	if err != nil {
+		return Result{Severity: Warn, Message: err.Error()}
+	}
+	msg := fmt.Sprintf("%.1f GB free", gb)
+	switch {
+	case gb < 1:
+		return Result{Severity: Fail, Message: msg, Fix: "free up disk space before installing"}
+	case gb < 5:
+		return Result{Severity: Warn, Message: msg, Fix: "Homebrew, Xcode and Node need several GB; free up disk space"}
+	}
+	return passf("%s", msg)
+}
+
+// brokenLinks returns the managed files of available modules whose symlink
+// target no longer exists.
+func brokenLinks() []File {
+	var broken []File
+	for _, m := range Available() {
+		fp, ok := m.(FileProvider)
+		if !ok {
+			continue
+		}
+		for _, f := range fp.Files() {
+			if state, _ := Status(f); state == StateBroken {
+				broken = append(broken, f)
+			}
+		}
+	}
+	return broken
+}
+
+func checkLinks() Result {
+	broken := brokenLinks()
+	if len(broken) == 0 {
+		return passf("no broken links")
+	}
+	var paths []string
+	for _, f := range broken {
+		paths = append(paths, f.Dest)
+	}
+	return Result{
+		Severity: Fail,
+		Message:  "broken links: " + strings.Join(paths, ", "),
+		Fix:      "henrik-os unlink, or henrik-os doctor --fix to restore copies",
+	}
+}
+
+func repairLinks(w io.Writer) error {
+	for _, f := range brokenLinks() {
+		if err := UnlinkFile(w, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
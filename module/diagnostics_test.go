@@ -0,0 +1,113 @@
+package module
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func usePlatform(t *testing.T, goos string) {
+	t.Helper()
+	prev := platform
+	platform = goos
+	t.Cleanup(func() { platform = prev })
+}
+
+func TestCoreDiagnostics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+		run   func() Result
+		want  Severity
+		msg   string
+	}{
+		{"fish login shell", func(t *testing.T) { t.Setenv("SHELL", "/opt/homebrew/bin/fish") }, checkShell, Pass, "/opt/homebrew/bin/fish"},
+		{"other login shell", func(t *testing.T) { t.Setenv("SHELL", "/bin/zsh") }, checkShell, Warn, "login shell is /bin/zsh"},
+		{"no login shell", func(t *testing.T) { t.Setenv("SHELL", "") }, checkShell, Warn, "login shell is unknown"},
+		{"xcode off macOS", func(t *testing.T) { usePlatform(t, "linux") }, checkXcode, Pass, "not needed on Linux"},
+		{"xcode installed", func(t *testing.T) {
+			usePlatform(t, "darwin")
+			newFakeBin(t).add(t, "xcode-select", "echo /Library/Developer/CommandLineTools")
+		}, checkXcode, Pass, "/Library/Developer/CommandLineTools"},
+		{"xcode missing", func(t *testing.T) {
+			usePlatform(t, "darwin")
+			newFakeBin(t).add(t, "xcode-select", "exit 2")
+		}, checkXcode, Fail, "not installed"},
+		{"ssh dir missing", func(t *testing.T) { t.Setenv("HOME", t.TempDir()) }, checkSSHDir, Warn, "~/.ssh does not exist"},
+		{"ssh dir open", func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			os.Mkdir(sshDir(), 0o755)
+		}, checkSSHDir, Fail, "mode is 0755"},
+		{"ssh dir private", func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			os.Mkdir(sshDir(), 0o700)
+		}, checkSSHDir, Pass, "0700"},
+		{"github reachable", func(t *testing.T) { useGitHubURL(t, server.URL) }, checkGitHub, Pass, server.URL},
+		{"github unreachable", func(t *testing.T) { useGitHubURL(t, "http://127.0.0.1:1") }, checkGitHub, Fail, "connection refused"},
+		{"brew on PATH", func(t *testing.T) {
+			usePlatform(t, "linux")
+			newFakeBin(t).add(t, "brew", "")
+		}, checkBrew, Pass, "/brew (prefix "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+			r := tt.run()
+			if r.Severity != tt.want || !strings.Contains(r.Message, tt.msg) {
+				t.Errorf("result = %v %q, want %v containing %q", r.Severity, r.Message, tt.want, tt.msg)
+			}
+			if r.Severity != Pass && r.Fix == "" {
+				t.Error("a problem was reported without a fix")
+			}
+		})
+	}
+}
+
+func useGitHubURL(t *testing.T, url string) {
+	t.Helper()
+	prev := githubURL
+	githubURL = url
+	t.Cleanup(func() { githubURL = prev })
+}
+
+func TestRepairSSHDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.Mkdir(sshDir(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := repairSSHDir(&out); err != nil {
+		t.Fatal(err)
+	}
+	if r := checkSSHDir(); r.Severity != Pass {
+		t.Errorf("after repair: %v %s", r.Severity, r.Message)
+	}
+	if !strings.Contains(out.String(), "chmod 700 "+filepath.Join(os.Getenv("HOME"), ".ssh")) {
+		t.Errorf("repair output = %q", out.String())
+	}
+}
+
+func TestRepairXcodeDoesNotWait(t *testing.T) {
+	usePlatform(t, "darwin")
+	bin := newFakeBin(t)
+	// The tools never appear, as when the dialog is dismissed.
+	bin.add(t, "xcode-select", `[ "$1" = "--install" ] || exit 2`)
+
+	var out strings.Builder
+	if err := repairXcode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"xcode-select --install"}; !reflect.DeepEqual(bin.calls(), want) {
+		t.Errorf("calls = %q, want %q", bin.calls(), want)
+	}
+	if !strings.Contains(out.String(), "run henrik-os doctor again") {
+		t.Errorf("output = %q", out.String())
+	}
+}
@@ -0,0 +1,44 @@
+package module
+
+import (
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Dnf is the Fedora/RHEL package manager. Changes run through sudo.
+type Dnf struct{}
+
+func (d *Dnf) Name() string { return "dnf" }
+
+func (d *Dnf) IsInstalled(pkg string) bool {
+	return exec.Command("rpm", "-q", pkg).Run() == nil
+}
+
+// Refresh is a no-op: dnf refreshes expired metadata by itself.
+func (d *Dnf) Refresh(w io.Writer) error { return nil }
+
+// Available reports whether an enabled repository provides pkg.
+func (d *Dnf) Available(pkg string) bool {
+	return exec.Command("dnf", "-q", "list", "--available", pkg).Run() == nil
+}
+
+func (d *Dnf) Install(w io.Writer, pkgs ...string) error {
+	return SudoRun(w, "dnf", append([]string{"install", "-y"}, pkgs...)...)
+}
+
+func (d *Dnf) Upgrade(w io.Writer, pkgs ...string) error {
+	return SudoRun(w, "dnf", append([]string{"upgrade", "-y"}, pkgs...)...)
+}
+
+func (d *Dnf) Remove(w io.Writer, pkgs ...string) error {
+	return SudoRun(w, "dnf", append([]string{"remove", "-y"}, pkgs...)...)
+}
+
+func (d *Dnf) List() ([]string, error) {
+	out, err := exec.Command("rpm", "-qa", "--qf", "%{NAME}\n").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
@@ -0,0 +1,73 @@
+package module
+
+import (
+	"fmt"
+	"io"
+)
+
+// Severity is the outcome of a diagnostic.
+type Severity int
+
+const (
+	Pass Severity = iota
+	Warn
+	Fail
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Pass:
+		return "pass"
+	case Warn:
+		return "warn"
+	case Fail:
+		return "fail"
+	}
+	return "unknown"
+}
+
+// Result is what a diagnostic found. Fix suggests how to resolve a warning
+// or failure.
+type Result struct {
+	Severity Severity
+	Message  string
+	Fix      string
+}
+
+// Diagnostic is a single health check run by `henrik-os doctor`. Repair,
+// when set, fixes the problem automatically for --fix.
+type Diagnostic struct {
+	Name   string
+	Run    func() Result
+	Repair func(w io.Writer) error
+}
+
+// Diagnoser is implemented by modules that contribute their own health
+// checks. Diagnostics should return nothing for modules that are not in
+// use on this machine.
+type Diagnoser interface {
+	Diagnostics() []Diagnostic
+}
+
+var diagnostics []Diagnostic
+
+// RegisterDiagnostic adds a core diagnostic that always runs.
+func RegisterDiagnostic(d Diagnostic) {
+	diagnostics = append(diagnostics, d)
+}
+
+// Diagnostics returns the core diagnostics followed by those contributed by
+// the given modules.
+func Diagnostics(modules []Module) []Diagnostic {
+	result := append([]Diagnostic(nil), diagnostics...)
+	for _, m := range modules {
+		if d, ok := m.(Diagnoser); ok {
+			result = append(result, d.Diagnostics()...)
+		}
+	}
+	return result
+}
+
+func passf(format string, args ...any) Result {
+	return Result{Severity: Pass, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,67 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// FakePackageManager keeps packages in memory and records every call. It
+// lets modules be exercised on machines without a package manager; select
+// it with HENRIK_OS_PACKAGE_MANAGER=fake.
+type FakePackageManager struct {
+	Installed map[string]bool
+	Calls     []string
+}
+
+// NewFakePackageManager returns a fake with the given packages installed.
+func NewFakePackageManager(installed ...string) *FakePackageManager {
+	f := &FakePackageManager{Installed: make(map[string]bool)}
+	for _, pkg := range installed {
+		f.Installed[pkg] = true
+	}
+	return f
+}
+
+func (f *FakePackageManager) Name() string { return "fake" }
+
+func (f *FakePackageManager) IsInstalled(pkg string) bool {
+	return f.Installed[pkg]
+}
+
+func (f *FakePackageManager) record(w io.Writer, op string, pkgs []string) {
+	call := op + " " + strings.Join(pkgs, " ")
+	f.Calls = append(f.Calls, call)
+	fmt.Fprintf(w, "  fake: %s\n", call)
+}
+
+func (f *FakePackageManager) Install(w io.Writer, pkgs ...string) error {
+	f.record(w, "install", pkgs)
+	for _, pkg := range pkgs {
+		f.Installed[pkg] = true
+	}
+	return nil
+}
+
+func (f *FakePackageManager) Upgrade(w io.Writer, pkgs ...string) error {
+	f.record(w, "upgrade", pkgs)
+	return nil
+}
+
+func (f *FakePackageManager) Remove(w io.Writer, pkgs ...string) error {
+	f.record(w, "remove", pkgs)
+	for _, pkg := range pkgs {
+		delete(f.Installed, pkg)
+	}
+	return nil
+}
+
+func (f *FakePackageManager) List() ([]string, error) {
+	var pkgs []string
+	for pkg := range f.Installed {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs, nil
+}
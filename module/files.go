@@ -0,0 +1,231 @@
+package module
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File describes a config file deployed from the configs tree.
+type File struct {
+	Src  string // path inside the configs tree (configs.FS by default)
+	Dest string // absolute destination path
+	Perm os.FileMode
+
+	// Block, when set, deploys the file as a managed block with this name
+	// inside Dest instead of owning the whole file. See WriteBlock.
+	Block string
+
+	// Previous lists earlier whole-file versions of a Block file. A
+	// destination starting with one of them has it replaced by the block.
+	Previous []string
+
+	// Merge, when set, applies the settings in Src onto the existing Dest
+	// instead of owning the whole file. See MergeFunc.
+	Merge MergeFunc
+
+	// FS, when set, is the tree Src is read from instead of the configs
+	// tree. Such files are always copied, never linked.
+	FS ConfigSource
+}
+
+func (f File) source() ConfigSource {
+	if f.FS != nil {
+		return f.FS
+	}
+	return source
+}
+
+// MergeFunc merges the managed settings into the existing content of a
+// file. It returns the merged content and the settings that changed.
+// existing is nil when the file does not exist yet.
+type MergeFunc func(existing, managed []byte) ([]byte, []string, error)
+
+// FileProvider is implemented by modules that deploy config files.
+type FileProvider interface {
+	Files() []File
+}
+
+// InstallFiles deploys every file the module manages, either as a copy of
+// the source config or, in link mode, as a symlink into the checkout.
+// Block and merged files are shared with other tools and are never linked.
+func InstallFiles(w io.Writer, p FileProvider) error {
+	for _, f := range p.Files() {
+		var err error
+		switch {
+		case f.Block != "":
+			err = writeBlockFile(w, f)
+		case f.Merge != nil:
+			err = writeMergedFile(w, f)
+		case linkRoot != "" && f.FS == nil:
+			err = LinkFile(w, f)
+		default:
+			err = WriteEmbedded(w, f.source(), f.Src, f.Dest, f.Perm)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBlockFile(w io.Writer, f File) error {
+	data, err := f.source().ReadFile(f.Src)
+	if err != nil {
+		return fmt.Errorf("reading source %s: %w", f.Src, err)
+	}
+	return WriteBlock(w, f.Dest, f.Block, string(data), f.Perm, f.Previous...)
+}
+
+// mergeFile returns the existing content of f's destination and the result
+// of merging its source into it.
+func mergeFile(f File) (existing, merged []byte, changed []string, err error) {
+	managed, err := f.source().ReadFile(f.Src)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading source %s: %w", f.Src, err)
+	}
+	existing, err = os.ReadFile(f.Dest)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, fmt.Errorf("reading %s: %w", f.Dest, err)
+	}
+	merged, changed, err = f.Merge(existing, managed)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("merging %s: %w", f.Dest, err)
+	}
+	return existing, merged, changed, nil
+}
+
+// writeMergedFile merges the source of f into its destination, listing
+// the settings that changed. The file is only rewritten (with backup) when
+// the merge changes it.
+func writeMergedFile(w io.Writer, f File) error {
+	existing, merged, changed, err := mergeFile(f)
+	if err != nil {
+		return err
+	}
+	if existing != nil && bytes.Equal(merged, existing) {
+		fmt.Fprintf(w, "  Up to date %s\n", f.Dest)
+		return nil
+	}
+	for _, key := range changed {
+		fmt.Fprintf(w, "    changed %s\n", key)
+	}
+	return BackupAndWrite(w, f.Dest, merged, f.Perm)
+}
+
+// FileState describes how a deployed file relates to its source.
+type FileState int
+
+const (
+	StateMissing    FileState = iota // nothing at the destination
+	StateCopy                        // regular file matching the source config
+	StateModified                    // regular file that differs from the source config
+	StateLinked                      // symlink into a configs checkout
+	StateBroken                      // symlink whose target does not exist
+	StateForeign                     // symlink pointing somewhere else
+	StateBlock                       // managed block matching the source config
+	StateStaleBlock                  // managed block that differs from the source config
+	StateMerged                      // file containing every managed setting
+	StateStaleMerge                  // file missing or overriding managed settings
+)
+
+func (s FileState) String() string {
+	switch s {
+	case StateMissing:
+		return "missing"
+	case StateCopy:
+		return "copy"
+	case StateModified:
+		return "modified"
+	case StateLinked:
+		return "linked"
+	case StateBroken:
+		return "broken link"
+	case StateForeign:
+		return "foreign link"
+	case StateBlock:
+		return "block"
+	case StateStaleBlock:
+		return "stale block"
+	case StateMerged:
+		return "merged"
+	case StateStaleMerge:
+		return "stale merge"
+	}
+	return "unknown"
+}
+
+// Status inspects the destination of f and reports its state. For symlinks
+// the target is returned as well.
+func Status(f File) (FileState, string) {
+	info, err := os.Lstat(f.Dest)
+	if err != nil {
+		return StateMissing, ""
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(f.Dest)
+		if err != nil {
+			return StateBroken, ""
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(f.Dest), target)
+		}
+		if _, err := os.Stat(target); err != nil {
+			return StateBroken, target
+		}
+		if !isLinkTarget(target, f.Src) {
+			return StateForeign, target
+		}
+		return StateLinked, target
+	}
+
+	if f.Block != "" {
+		return blockStatus(f)
+	}
+	if f.Merge != nil {
+		existing, merged, _, err := mergeFile(f)
+		if err != nil || !bytes.Equal(existing, merged) {
+			return StateStaleMerge, ""
+		}
+		return StateMerged, ""
+	}
+
+	want, err := f.source().ReadFile(f.Src)
+	if err != nil {
+		return StateModified, ""
+	}
+	got, err := os.ReadFile(f.Dest)
+	if err != nil || !bytes.Equal(got, want) {
+		return StateModified, ""
+	}
+	return StateCopy, ""
+}
+
+func blockStatus(f File) (FileState, string) {
+	got, err := os.ReadFile(f.Dest)
+	if err != nil {
+		return StateMissing, ""
+	}
+	body, found := ExtractBlock(got, f.Block)
+	if !found {
+		return StateMissing, ""
+	}
+	want, err := f.source().ReadFile(f.Src)
+	if err != nil || strings.TrimRight(body, "\n") != strings.TrimRight(string(want), "\n") {
+		return StateStaleBlock, ""
+	}
+	return StateBlock, ""
+}
+
+// isLinkTarget reports whether target is the checkout file for src. Links
+// into any other directory, including other checkouts with the same
+// layout, are not henrik-os's, and neither is any link while the checkout
+// is unknown.
+func isLinkTarget(target, src string) bool {
+	root := checkoutRoot()
+	return root != "" && filepath.Clean(target) == filepath.Join(root, src)
+}
@@ -5,10 +5,10 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
 	"strings"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"time"
 )
 
 func init() {
@@ -20,65 +20,186 @@ type Fish struct{}
 func (f *Fish) Name() string         { return "Fish Shell" }
 func (f *Fish) ID() string           { return "fish" }
 func (f *Fish) Description() string  { return "Configure Fish shell, functions, and Oh My Fish" }
-func (f *Fish) Dependencies() []string { return []string{"homebrew"} }
+func (f *Fish) Dependencies() []string { return brewDeps() }
+func (f *Fish) Tags() []string         { return []string{"shell"} }
 
-func (f *Fish) Install(w io.Writer) error {
-	fishPath := "/opt/homebrew/bin/fish"
+func (f *Fish) Files() []File {
+	configDir := filepath.Join(HomeDir(), ".config/fish")
+	funcDir := filepath.Join(configDir, "functions")
+
+	files := []File{
+		{Src: "fish/config.fish", Dest: filepath.Join(configDir, "config.fish"), Perm: 0o644},
+	}
+	functions := []string{
+		"y.fish", "b.fish", "fuck.fish",
+		"_fzf_compgen_path.fish", "_fzf_compgen_dir.fish",
+	}
+	for _, fn := range functions {
+		files = append(files, File{Src: "fish/functions/" + fn, Dest: filepath.Join(funcDir, fn), Perm: 0o644})
+	}
+	return files
+}
+
+func (f *Fish) Packages() []string {
+	return []string{"fish", "fzf", "fd", "zoxide", "direnv", "eza"}
+}
+
+// Check reports whether fish is installed.
+func (f *Fish) Check() error {
+	_, err := exec.LookPath("fish")
+	return err
+}
+
+// Verify parses every deployed fish file without running it.
+func (f *Fish) Verify() error {
+	if f.Check() != nil {
+		return nil
+	}
+	for _, file := range f.Files() {
+		if err := verifyCommand(30*time.Second, "fish", "--no-execute", file.Dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fish) Steps() []Step {
+	return []Step{{
+		Title:  "Fish shell",
+		Action: "Open a new terminal to start using fish",
+		Done:   func() bool { return filepath.Base(os.Getenv("SHELL")) == "fish" },
+	}}
+}
+
+// Diagnostics checks that an installed fish can be used as a login shell.
+func (f *Fish) Diagnostics() []Diagnostic {
+	if f.Check() != nil {
+		return nil
+	}
+	return []Diagnostic{{
+		Name: "fish in /etc/shells",
+		Run: func() Result {
+			shells, _ := os.ReadFile("/etc/shells")
+			if !strings.Contains(string(shells), f.fishPath()) {
+				return Result{Severity: Fail, Message: f.fishPath() + " is not a permitted login shell", Fix: "henrik-os install fish"}
+			}
+			return passf("%s", f.fishPath())
+		},
+	}}
+}
+
+func (f *Fish) fishPath() string {
 	if p, err := exec.LookPath("fish"); err == nil {
-		fishPath = p
+		return p
 	}
+	if Platform() == "darwin" {
+		return "/opt/homebrew/bin/fish"
+	}
+	return "/usr/bin/fish"
+}
 
-	// Add to /etc/shells if missing
+// NeedsSudo reports whether fish still has to be added to /etc/shells or
+// made the login shell.
+func (f *Fish) NeedsSudo() bool {
+	return f.needsShellsEntry() || f.needsChsh()
+}
+
+func (f *Fish) needsShellsEntry() bool {
 	shells, _ := os.ReadFile("/etc/shells")
-	if !strings.Contains(string(shells), fishPath) {
-		fmt.Fprintln(w, "  Adding fish to /etc/shells...")
-		cmd := exec.Command("sudo", "sh", "-c",
-			fmt.Sprintf("echo '%s' >> /etc/shells", fishPath))
-		cmd.Stdout = w
-		cmd.Stderr = w
-		_ = cmd.Run()
-	}
+	return !strings.Contains(string(shells), f.fishPath())
+}
 
-	// Set as default shell
-	if os.Getenv("SHELL") != fishPath {
-		fmt.Fprintln(w, "  Setting fish as default shell...")
-		cmd := exec.Command("chsh", "-s", fishPath)
-		cmd.Stdout = w
-		cmd.Stderr = w
-		_ = cmd.Run()
+func (f *Fish) needsChsh() bool {
+	return os.Getenv("SHELL") != f.fishPath()
+}
+
+// chshArgs makes fish the login shell of the current user.
+func (f *Fish) chshArgs() []string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
 	}
-	fmt.Fprintln(w, "  Fish is default shell")
+	return []string{"chsh", "-s", f.fishPath(), name}
+}
 
-	// Remove legacy aliases file
-	os.Remove(filepath.Join(HomeDir(), ".config/fish/functions/aliases.fish"))
+const (
+	omfInstallScript = "curl -sL https://raw.githubusercontent.com/oh-my-fish/oh-my-fish/master/bin/install | fish /dev/stdin --noninteractive"
+	omfPluginScript  = "omf install git"
+)
 
-	configDir := filepath.Join(HomeDir(), ".config/fish")
-	funcDir := filepath.Join(configDir, "functions")
+func omfInstalled() bool {
+	_, err := os.Stat(filepath.Join(HomeDir(), ".local/share/omf"))
+	return err == nil
+}
+
+// Commands lists what Install runs, leaving out steps that are already
+// done.
+func (f *Fish) Commands() []string {
+	var cmds []string
+	if f.needsShellsEntry() {
+		cmds = append(cmds, "sudo tee /etc/shells (add "+f.fishPath()+")")
+	}
+	if f.needsChsh() {
+		cmds = append(cmds, "sudo "+strings.Join(f.chshArgs(), " "))
+	}
+	if !omfInstalled() {
+		cmds = append(cmds, "fish -c '"+omfInstallScript+"'")
+	}
+	return append(cmds, "fish -c '"+omfPluginScript+"'")
+}
 
-	// Write config.fish
-	if err := WriteEmbedded(w, &configs.FS, "fish/config.fish",
-		filepath.Join(configDir, "config.fish"), 0o644); err != nil {
+func (f *Fish) Install(w io.Writer) error {
+	if err := EnsurePackages(w, f.Packages()...); err != nil {
 		return err
 	}
 
-	// Write fish functions
-	functions := []string{
-		"y.fish", "b.fish", "fuck.fish",
-		"_fzf_compgen_path.fish", "_fzf_compgen_dir.fish",
+	fishPath := f.fishPath()
+	if _, err := os.Stat(fishPath); err != nil {
+		return fmt.Errorf("fish not found at %s", fishPath)
 	}
-	for _, fn := range functions {
-		if err := WriteEmbedded(w, &configs.FS, "fish/functions/"+fn,
-			filepath.Join(funcDir, fn), 0o644); err != nil {
-			return err
+
+	// Add to /etc/shells if missing
+	if f.needsShellsEntry() {
+		// Never write the block without the existing entries: a failed read
+		// would otherwise replace the file with fish alone. Privileged steps
+		// that fail, e.g. without sudo, are skipped and the rest of the
+		// module still installs.
+		shells, err := os.ReadFile("/etc/shells")
+		if err != nil {
+			fmt.Fprintf(w, "  Skipping /etc/shells: %v\n", err)
+		} else {
+			fmt.Fprintln(w, "  Adding fish to /etc/shells...")
+			if err := SudoWriteFile(w, "/etc/shells", RenderBlock(shells, f.ID(), fishPath)); err != nil {
+				fmt.Fprintf(w, "  Skipping /etc/shells: %v\n", err)
+			}
 		}
 	}
 
+	// Set as default shell
+	if f.needsChsh() {
+		fmt.Fprintln(w, "  Setting fish as default shell...")
+		args := f.chshArgs()
+		if err := SudoRun(w, args[0], args[1:]...); err != nil {
+			fmt.Fprintf(w, "  Skipping default shell: %v\n", err)
+		} else {
+			fmt.Fprintln(w, "  Fish is default shell")
+		}
+	} else {
+		fmt.Fprintln(w, "  Fish is default shell")
+	}
+
+	// Remove legacy aliases file
+	os.Remove(filepath.Join(HomeDir(), ".config/fish/functions/aliases.fish"))
+
+	// Write config.fish and functions
+	if err := InstallFiles(w, f); err != nil {
+		return err
+	}
+
 	// Oh My Fish
-	omfDir := filepath.Join(HomeDir(), ".local/share/omf")
-	if _, err := os.Stat(omfDir); os.IsNotExist(err) {
+	if !omfInstalled() {
 		fmt.Fprintln(w, "  Installing Oh My Fish...")
-		cmd := exec.Command("fish", "-c",
-			"curl -sL https://raw.githubusercontent.com/oh-my-fish/oh-my-fish/master/bin/install | fish /dev/stdin --noninteractive")
+		cmd := exec.Command("fish", "-c", omfInstallScript)
 		cmd.Stdout = w
 		cmd.Stderr = w
 		_ = cmd.Run()
@@ -87,7 +208,7 @@ func (f *Fish) Install(w io.Writer) error {
 	}
 
 	// Install git plugin
-	cmd := exec.Command("fish", "-c", "omf install git")
+	cmd := exec.Command("fish", "-c", omfPluginScript)
 	cmd.Stdout = w
 	cmd.Stderr = w
 	_ = cmd.Run()
@@ -3,9 +3,10 @@ package module
 import (
 	"fmt"
 	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"time"
 )
 
 func init() {
@@ -18,19 +19,41 @@ func (g *Ghostty) Name() string         { return "Ghostty" }
 func (g *Ghostty) ID() string           { return "ghostty" }
 func (g *Ghostty) Description() string  { return "Configure Ghostty terminal" }
 func (g *Ghostty) Dependencies() []string { return nil }
+func (g *Ghostty) SoftDependencies() []string { return []string{"fish"} }
+func (g *Ghostty) Tags() []string         { return []string{"terminal"} }
 
-func (g *Ghostty) Install(w io.Writer) error {
+func (g *Ghostty) Files() []File {
 	configDir := filepath.Join(HomeDir(), ".config/ghostty")
-	files := []string{"config"}
-	for _, f := range files {
-		dest := filepath.Join(configDir, f)
-		if err := WriteEmbedded(w, &configs.FS, "ghostty/"+f, dest, 0o644); err != nil {
-			return err
+	var files []File
+	for _, f := range []string{"config"} {
+		files = append(files, File{Src: "ghostty/" + f, Dest: filepath.Join(configDir, f), Perm: 0o644})
+	}
+	// The hacker function is only useful with fish
+	if !Present("fish") {
+		return files
+	}
+	files = append(files, File{
+		Src:  "fish/functions/hacker.fish",
+		Dest: filepath.Join(HomeDir(), ".config/fish/functions/hacker.fish"),
+		Perm: 0o644,
+	})
+	return files
+}
+
+// Verify asks Ghostty to validate its config when the CLI is available.
+func (g *Ghostty) Verify() error {
+	bin, err := exec.LookPath("ghostty")
+	if err != nil {
+		bin = "/Applications/Ghostty.app/Contents/MacOS/ghostty"
+		if _, err := os.Stat(bin); err != nil {
+			return nil
 		}
 	}
-	// Deploy hacker function (assumes fish shell)
-	hackerDest := filepath.Join(HomeDir(), ".config/fish/functions/hacker.fish")
-	if err := WriteEmbedded(w, &configs.FS, "fish/functions/hacker.fish", hackerDest, 0o644); err != nil {
+	return verifyCommand(30*time.Second, bin, "+validate-config", "--config-file="+g.Files()[0].Dest)
+}
+
+func (g *Ghostty) Install(w io.Writer) error {
+	if err := InstallFiles(w, g); err != nil {
 		return err
 	}
 
@@ -3,9 +3,11 @@ package module
 import (
 	"fmt"
 	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"strings"
+	"time"
 )
 
 func init() {
@@ -17,18 +19,204 @@ type GitConfig struct{}
 func (g *GitConfig) Name() string         { return "Git Config" }
 func (g *GitConfig) ID() string           { return "git" }
 func (g *GitConfig) Description() string  { return "Configure Git global settings" }
-func (g *GitConfig) Dependencies() []string { return nil }
+func (g *GitConfig) Tags() []string         { return []string{"system"} }
 
-func (g *GitConfig) Install(w io.Writer) error {
+// Dependencies requires the ssh module when commits are signed, so the
+// signing key exists before it is referenced.
+func (g *GitConfig) Dependencies() []string {
+	if manifest.Git.Signing {
+		return []string{"ssh"}
+	}
+	return nil
+}
+
+func (g *GitConfig) Packages() []string { return []string{"git"} }
+
+func (g *GitConfig) Files() []File {
 	home := HomeDir()
+	gitconfig := File{Src: "git/.gitconfig", Dest: filepath.Join(home, ".gitconfig"), Perm: 0o644, Merge: g.merge}
+	files := []File{
+		gitconfig,
+		{Src: "git/.gitignore_global", Dest: filepath.Join(home, ".gitignore_global"), Perm: 0o644},
+	}
+	ids := manifest.sshIdentities()
+	includes := identityIncludes(ids)
+	if !manifest.Git.Signing && len(includes) == 0 {
+		return files
+	}
 
-	if err := WriteEmbedded(w, &configs.FS, "git/.gitconfig",
-		filepath.Join(home, ".gitconfig"), 0o644); err != nil {
-		return err
+	// Add the signing settings and the includeIf sections to the settings
+	// merged into ~/.gitconfig.
+	base, _ := source.ReadFile(gitconfig.Src)
+	config := string(base)
+	if manifest.Git.Signing {
+		config += signingConfig()
+	}
+	for _, id := range includes {
+		config += fmt.Sprintf("[includeIf \"gitdir:%s\"]\n\tpath = %s\n", id.Gitdir, identityIncludePath(id))
+	}
+	files[0].FS = generatedSource{gitconfig.Src: config}
+
+	for _, id := range includes {
+		src := "git/identities/" + id.Name + ".gitconfig"
+		files = append(files, File{
+			Src:   src,
+			Dest:  identityIncludePath(id),
+			Perm:  0o644,
+			Block: g.ID(),
+			FS:    generatedSource{src: identityConfig(id)},
+		})
+	}
+	if manifest.Git.Signing {
+		files = append(files, File{
+			Src:   "git/allowed_signers",
+			Dest:  allowedSignersPath(),
+			Perm:  0o644,
+			Block: g.ID(),
+			FS:    generatedSource{"git/allowed_signers": allowedSigners(ids, gitUserEmail(base))},
+		})
+	}
+	return files
+}
+
+// merge applies the managed settings onto the user's ~/.gitconfig, keeping
+// everything else other tools have added. The managed block earlier
+// versions wrote is unwrapped first so its settings are merged in place.
+func (g *GitConfig) merge(existing, managed []byte) ([]byte, []string, error) {
+	return mergeGitConfig(unwrapBlock(existing, g.ID()), managed)
+}
+
+// Verify checks that git can still parse ~/.gitconfig.
+func (g *GitConfig) Verify() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil
+	}
+	return verifyCommand(10*time.Second, "git", "config", "--file", g.Files()[0].Dest, "--list")
+}
+
+// identityIncludes returns the identities that apply to a directory tree.
+func identityIncludes(ids []Identity) []Identity {
+	var result []Identity
+	for _, id := range ids {
+		if id.Gitdir != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func identityIncludePath(id Identity) string {
+	return filepath.Join(HomeDir(), ".config/git/identities", id.Name+".gitconfig")
+}
+
+// identityConfig sets the email, and the signing key when commits are
+// signed, for repositories under the identity's gitdir.
+func identityConfig(id Identity) string {
+	config := "[user]\n\temail = " + id.Email + "\n"
+	if manifest.Git.Signing {
+		config += "\tsigningkey = " + id.KeyPath() + ".pub\n"
+	}
+	return config
+}
+
+func allowedSignersPath() string {
+	return filepath.Join(HomeDir(), ".config/git/allowed_signers")
+}
+
+// signingConfig configures git to sign commits with the primary SSH key.
+func signingConfig() string {
+	return fmt.Sprintf(`[gpg]
+	format = ssh
+[gpg "ssh"]
+	allowedSignersFile = %s
+[user]
+	signingkey = %s.pub
+[commit]
+	gpgsign = true
+`, allowedSignersPath(), primaryKey())
+}
+
+// allowedSigners renders an allowed_signers line for each identity whose
+// public key exists, so git can verify the user's own signatures. Git
+// matches the principal against the committer email: the identity's email
+// inside its gitdir, and the global user.email everywhere else.
+func allowedSigners(ids []Identity, email string) string {
+	var b strings.Builder
+	for _, id := range ids {
+		principal := email
+		if id.Gitdir != "" || principal == "" {
+			principal = id.Email
+		}
+		if principal == "" {
+			continue
+		}
+		pub, err := os.ReadFile(id.KeyPath() + ".pub")
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(pub))
+		if len(fields) < 2 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s namespaces=\"git\" %s %s\n", principal, fields[0], fields[1])
+	}
+	return b.String()
+}
+
+// gitUserEmail returns the user.email a git config file sets.
+func gitUserEmail(config []byte) string {
+	var email string
+	for _, l := range parseGitConfig(config) {
+		if l.matches("user.email") {
+			email = l.value
+		}
+	}
+	return email
+}
+
+func (g *GitConfig) Steps() []Step {
+	return []Step{{
+		Title:  "Git signing (optional)",
+		Action: "set signing = true under [git] in " + ManifestPath(),
+		Done: func() bool {
+			out, _ := exec.Command("git", "config", "--global", "commit.gpgsign").Output()
+			return strings.TrimSpace(string(out)) == "true"
+		},
+	}}
+}
+
+// Diagnostics checks that commits will carry an identity.
+func (g *GitConfig) Diagnostics() []Diagnostic {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil
+	}
+	return []Diagnostic{{
+		Name: "Git identity",
+		Run: func() Result {
+			var missing []string
+			for _, key := range []string{"user.name", "user.email"} {
+				if out, _ := exec.Command("git", "config", "--global", key).Output(); strings.TrimSpace(string(out)) == "" {
+					missing = append(missing, key)
+				}
+			}
+			if len(missing) > 0 {
+				return Result{Severity: Warn, Message: strings.Join(missing, ", ") + " not set", Fix: `git config --global user.email "you@example.com"`}
+			}
+			return passf("user.name and user.email set")
+		},
+	}}
+}
+
+func (g *GitConfig) Install(w io.Writer) error {
+	// git often comes with the system, e.g. from the Xcode command line
+	// tools before Homebrew is installed; only install it when missing.
+	if _, err := exec.LookPath("git"); err != nil {
+		if err := EnsurePackages(w, g.Packages()...); err != nil {
+			return err
+		}
 	}
 
-	if err := WriteEmbedded(w, &configs.FS, "git/.gitignore_global",
-		filepath.Join(home, ".gitignore_global"), 0o644); err != nil {
+	if err := InstallFiles(w, g); err != nil {
 		return err
 	}
 
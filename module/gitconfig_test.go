@@ -0,0 +1,137 @@
+package module
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAllowedSigners(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0o700)
+	for key, pub := range map[string]string{
+		"id_ed25519":      "ssh-ed25519 AAAAprimary user@host\n",
+		"id_ed25519_work": "ssh-ed25519 AAAAwork\n",
+	} {
+		os.WriteFile(filepath.Join(home, ".ssh", key+".pub"), []byte(pub), 0o644)
+	}
+
+	ids := []Identity{
+		{Name: "default", Key: "id_ed25519"},
+		{Name: "work", Email: "me@work.com", Key: "id_ed25519_work", Gitdir: "~/work/"},
+		{Name: "missing", Email: "me@other.com", Key: "id_ed25519_missing"},
+	}
+	email := gitUserEmail([]byte("[core]\n\teditor = vim\n[user]\n\temail = me@example.com\n\tname = Me\n"))
+	if email != "me@example.com" {
+		t.Fatalf("gitUserEmail = %q", email)
+	}
+
+	// The principal is the committer email, never the key comment.
+	want := `me@example.com namespaces="git" ssh-ed25519 AAAAprimary
+me@work.com namespaces="git" ssh-ed25519 AAAAwork
+`
+	if got := allowedSigners(ids, email); got != want {
+		t.Errorf("allowed_signers =\n%s\nwant\n%s", got, want)
+	}
+
+	// Without a user.email the identity's own email is used, and identities
+	// without any email are left out.
+	want = "me@work.com namespaces=\"git\" ssh-ed25519 AAAAwork\n"
+	if got := allowedSigners(ids, ""); got != want {
+		t.Errorf("allowed_signers without user.email =\n%s\nwant\n%s", got, want)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGitConfigGolden installs the git files for two identities with
+// signing on and compares the generated ones, with the home directory
+// shown as ~, to testdata/gitconfig. Run go test -update to accept changes.
+func TestGitConfigGolden(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	doc, err := decodeTOML([]byte(`
+[[identities]]
+name = "personal"
+email = "me@example.com"
+gitdir = "~/src"
+
+[[identities]]
+name = "work"
+email = "me@work.com"
+gitdir = "~/work/"
+
+[git]
+signing = true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseManifest(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := manifest
+	manifest = m
+	t.Cleanup(func() { manifest = prev })
+
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0o700)
+	for _, id := range manifest.sshIdentities() {
+		os.WriteFile(id.KeyPath()+".pub", []byte("ssh-ed25519 AAAA"+id.Name+" "+id.Email+"\n"), 0o644)
+	}
+
+	g := &GitConfig{}
+	if err := InstallFiles(io.Discard, g); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range g.Files() {
+		if f.FS == nil {
+			continue // copied verbatim from configs
+		}
+		data, err := os.ReadFile(f.Dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.ReplaceAll(string(data), home, "~")
+		golden := filepath.Join("testdata/gitconfig", strings.TrimPrefix(filepath.Base(f.Dest), ".")+".golden")
+		if *updateGolden {
+			os.MkdirAll(filepath.Dir(golden), 0o755)
+			if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%s =\n%s\nwant (%s)\n%s", f.Dest, got, golden, want)
+		}
+	}
+}
+
+// A fresh Mac has git from the command line tools before Homebrew exists.
+func TestGitInstallWithoutPackageManager(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("HENRIK_OS_PACKAGE_MANAGER", "")
+	usePlatform(t, "darwin")
+	usePackageManager(t, nil)
+	bin := newFakeBin(t)
+	bin.add(t, "git", "")
+	t.Setenv("PATH", bin.dir)
+	if SystemPackageManager() != nil {
+		t.Skip("a package manager is installed on this machine")
+	}
+
+	if err := (&GitConfig{}).Install(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(HomeDir(), ".gitconfig")); err != nil {
+		t.Error(err)
+	}
+}
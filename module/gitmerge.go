@@ -0,0 +1,319 @@
+package module
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gitLine is one logical line of a git config file. Continuation lines are
+// folded into the line they continue.
+type gitLine struct {
+	text    string
+	header  bool   // section header
+	section string // lower-cased section name of the header or variable
+	sub     string // subsection, case-sensitive
+	name    string // variable name as written, empty unless a variable
+	value   string // unquoted value without comments
+}
+
+func (l gitLine) isVar() bool { return l.name != "" }
+
+// key returns the dotted name git uses for the variable, e.g.
+// includeIf.gitdir:~/work/.path.
+func (l gitLine) key() string {
+	if l.sub != "" {
+		return l.section + "." + l.sub + "." + l.name
+	}
+	return l.section + "." + l.name
+}
+
+func (l gitLine) sameSection(o gitLine) bool {
+	return l.section == o.section && l.sub == o.sub
+}
+
+// parseGitConfig splits a git config file into lines, tracking the section
+// each variable belongs to. Anything it does not understand is kept as an
+// opaque line.
+func parseGitConfig(data []byte) []gitLine {
+	var lines []gitLine
+	var section, sub string
+	raw := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		raw = nil
+	}
+	for i := 0; i < len(raw); i++ {
+		text := raw[i]
+		for strings.HasSuffix(text, "\\") && i+1 < len(raw) {
+			i++
+			text += "\n" + raw[i]
+		}
+		line := gitLine{text: text}
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';':
+		case trimmed[0] == '[':
+			if s, ss, ok := parseGitHeader(trimmed); ok {
+				section, sub = s, ss
+				line.header = true
+			}
+			line.section, line.sub = section, sub
+		default:
+			name, value, _ := strings.Cut(trimmed, "=")
+			line.name = strings.TrimSpace(name)
+			line.value = gitValue(value)
+			line.section, line.sub = section, sub
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseGitHeader parses [section], [section "subsection"] and the legacy
+// [section.subsection].
+func parseGitHeader(s string) (section, sub string, ok bool) {
+	end := strings.LastIndex(s, "]")
+	if end < 0 {
+		return "", "", false
+	}
+	inner := s[1:end]
+	if name, quoted, found := strings.Cut(inner, " "); found {
+		quoted = strings.TrimSpace(quoted)
+		if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+			return "", "", false
+		}
+		r := strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+		return strings.ToLower(name), r.Replace(quoted[1 : len(quoted)-1]), true
+	}
+	if name, legacy, found := strings.Cut(inner, "."); found {
+		return strings.ToLower(name), strings.ToLower(legacy), true
+	}
+	return strings.ToLower(inner), "", true
+}
+
+// gitValue returns the value of a variable as git reads it: quotes and
+// escapes resolved, comments and surrounding whitespace removed.
+func gitValue(raw string) string {
+	var b strings.Builder
+	quoted := false
+	pending := "" // whitespace that only counts if more value follows
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+			continue
+		case c == '\\' && i+1 < len(raw):
+			i++
+			switch raw[i] {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case '\n':
+				continue
+			default:
+				c = raw[i]
+			}
+		case !quoted && (c == '#' || c == ';'):
+			return b.String()
+		case !quoted && (c == ' ' || c == '\t'):
+			if b.Len() > 0 {
+				pending += string(c)
+			}
+			continue
+		}
+		b.WriteString(pending)
+		pending = ""
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// gitOwnedMarker starts the comment listing the variables henrik-os set in
+// a merged file, so they can be removed once they are no longer managed.
+const gitOwnedMarker = "# henrik-os manages"
+
+// splitGitKey splits a dotted key as returned by gitLine.key into section,
+// subsection and variable name. The subsection may itself contain dots.
+func splitGitKey(key string) (section, sub, name string) {
+	section, rest, _ := strings.Cut(key, ".")
+	name = rest
+	if i := strings.LastIndex(rest, "."); i >= 0 {
+		sub, name = rest[:i], rest[i+1:]
+	}
+	return strings.ToLower(section), sub, name
+}
+
+// matches reports whether l is the variable named by key. Section and
+// variable names are case-insensitive.
+func (l gitLine) matches(key string) bool {
+	section, sub, name := splitGitKey(key)
+	return l.isVar() && l.section == section && l.sub == sub && strings.EqualFold(l.name, name)
+}
+
+// ownedKeys reads the variables listed in the marker comment.
+func ownedKeys(lines []gitLine) []string {
+	var keys []string
+	for _, l := range lines {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(l.text), gitOwnedMarker)
+		if !ok {
+			continue
+		}
+		for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+			q, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				break
+			}
+			key, _ := strconv.Unquote(q)
+			keys = append(keys, key)
+			rest = rest[len(q):]
+		}
+	}
+	return keys
+}
+
+// mergeGitConfig applies every variable set in managed onto existing. Each
+// variable replaces the last occurrence of the same key, which is the one
+// git uses, or is added to its section. A key managed set more than once,
+// such as a credential.helper list reset by an empty value, is multi-valued:
+// all its occurrences are replaced by the managed values, in order.
+// Variables an earlier merge set but managed no longer does are removed,
+// along with sections left empty. What henrik-os never set is left
+// untouched, including comments and formatting. The variables set are
+// recorded in a marker comment on the first line.
+func mergeGitConfig(existing, managed []byte) ([]byte, []string, error) {
+	lines := parseGitConfig(existing)
+	previous := ownedKeys(lines)
+	lines = slices.DeleteFunc(lines, func(l gitLine) bool {
+		return strings.HasPrefix(strings.TrimSpace(l.text), gitOwnedMarker)
+	})
+	want := parseGitConfig(managed)
+
+	// Group the managed values by key, remembering the section header each
+	// key first appeared under.
+	type setting struct {
+		header gitLine
+		values []gitLine
+	}
+	var settings []*setting
+	var header gitLine
+	for _, w := range want {
+		if w.header {
+			header = w
+		}
+		if !w.isVar() {
+			continue
+		}
+		i := slices.IndexFunc(settings, func(s *setting) bool { return s.values[0].sameVar(w) })
+		if i < 0 {
+			settings = append(settings, &setting{header: header})
+			i = len(settings) - 1
+		}
+		settings[i].values = append(settings[i].values, w)
+	}
+
+	var owned, changed []string
+	for _, s := range settings {
+		owned = append(owned, s.values[0].key())
+	}
+
+	// Drop variables that are no longer managed
+	for _, key := range previous {
+		if slices.ContainsFunc(want, func(w gitLine) bool { return w.matches(key) }) {
+			continue
+		}
+		n := len(lines)
+		lines = slices.DeleteFunc(lines, func(l gitLine) bool { return l.matches(key) })
+		if len(lines) < n {
+			lines = dropEmptySection(lines, key)
+			changed = append(changed, key)
+		}
+	}
+
+	for _, s := range settings {
+		first := s.values[0]
+		var at []int
+		for i, l := range lines {
+			if l.sameVar(first) {
+				at = append(at, i)
+			}
+		}
+		if len(s.values) == 1 && len(at) > 0 {
+			at = at[len(at)-1:]
+		}
+		if slices.EqualFunc(at, s.values, func(i int, w gitLine) bool { return lines[i].value == w.value }) {
+			continue
+		}
+
+		var pos int
+		values := slices.Clone(s.values)
+		if len(at) > 0 {
+			pos = at[0]
+			indent := lines[pos].text[:len(lines[pos].text)-len(strings.TrimLeft(lines[pos].text, " \t"))]
+			for i := range values {
+				values[i].text = indent + strings.TrimSpace(values[i].text)
+			}
+			for _, i := range slices.Backward(at) {
+				lines = slices.Delete(lines, i, i+1)
+			}
+		} else if end := sectionEnd(lines, first); end >= 0 {
+			pos = end + 1
+		} else {
+			pos = len(lines)
+			values = append([]gitLine{s.header}, values...)
+		}
+		lines = slices.Insert(lines, pos, values...)
+		changed = append(changed, first.key())
+	}
+
+	var b strings.Builder
+	b.WriteString(gitOwnedMarker)
+	for _, key := range owned {
+		b.WriteString(" " + strconv.Quote(key))
+	}
+	b.WriteByte('\n')
+	for _, l := range lines {
+		b.WriteString(l.text)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), changed, nil
+}
+
+// sameVar reports whether l and o are variables with the same key.
+func (l gitLine) sameVar(o gitLine) bool {
+	return l.isVar() && o.isVar() && l.sameSection(o) && strings.EqualFold(l.name, o.name)
+}
+
+// sectionEnd returns the index of the last header or variable in the
+// section of l, or -1 if the section is not present.
+func sectionEnd(lines []gitLine, l gitLine) int {
+	end := -1
+	for i, o := range lines {
+		if o.sameSection(l) && (o.header || o.isVar()) {
+			end = i
+		}
+	}
+	return end
+}
+
+// dropEmptySection removes the header of key's section when nothing but
+// blank lines is left in it.
+func dropEmptySection(lines []gitLine, key string) []gitLine {
+	section, sub, _ := splitGitKey(key)
+	for i, l := range lines {
+		if !l.header || l.section != section || l.sub != sub {
+			continue
+		}
+		j := i + 1
+		for j < len(lines) && !lines[j].header && strings.TrimSpace(lines[j].text) == "" {
+			j++
+		}
+		if j == len(lines) || lines[j].header {
+			return slices.Delete(lines, i, i+1)
+		}
+	}
+	return lines
+}
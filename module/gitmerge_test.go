@@ -0,0 +1,170 @@
+package module
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitConfig(t *testing.T) {
+	data := `# comment
+[core]
+	editor = vim ; trailing comment
+	pager = "less -R # not a comment"
+	bare
+[remote "origin"]
+	url = git@github.com:me/app.git
+[Branch.Main]
+	remote = origin
+[alias]
+	lg = log \
+--oneline
+	say = "a \"quoted\" \\ word\tend"
+`
+	type parsed struct{ key, value string }
+	var got []parsed
+	for _, l := range parseGitConfig([]byte(data)) {
+		if l.isVar() {
+			got = append(got, parsed{l.key(), l.value})
+		}
+	}
+	want := []parsed{
+		{"core.editor", "vim"},
+		{"core.pager", "less -R # not a comment"},
+		{"core.bare", ""},
+		{"remote.origin.url", "git@github.com:me/app.git"},
+		{"branch.main.remote", "origin"},
+		{"alias.lg", "log --oneline"},
+		{"alias.say", "a \"quoted\" \\ word\tend"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsed\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestParseGitConfigRoundTrip(t *testing.T) {
+	data := "[a]\n\tx = 1 \\\n  2\n; c\n\n[b \"s p\"]\n\ty\n"
+	var b strings.Builder
+	for _, l := range parseGitConfig([]byte(data)) {
+		b.WriteString(l.text + "\n")
+	}
+	if b.String() != data {
+		t.Errorf("round trip changed the file:\n%q\nwant\n%q", b.String(), data)
+	}
+}
+
+func TestMergeGitConfig(t *testing.T) {
+	managed := "[core]\n\tautocrlf = input\n[user]\n\temail = me@example.com\n"
+	marker := gitOwnedMarker + ` "core.autocrlf" "user.email"` + "\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		managed  string
+		want     string
+		changed  []string
+	}{
+		{
+			name:    "missing file",
+			managed: managed,
+			want:    marker + managed,
+			changed: []string{"core.autocrlf", "user.email"},
+		},
+		{
+			name:     "keeps unknown sections and comments",
+			existing: "[credential]\n\thelper = osxkeychain\n[Core]\n\t# mine\n    autocrlf = true ; old\n",
+			managed:  managed,
+			want:     marker + "[credential]\n\thelper = osxkeychain\n[Core]\n\t# mine\n    autocrlf = input\n[user]\n\temail = me@example.com\n",
+			changed:  []string{"core.autocrlf", "user.email"},
+		},
+		{
+			name:     "adds to an existing section",
+			existing: "[user]\n\tname = Me\n[core]\n\tautocrlf = \"input\"\n",
+			managed:  managed,
+			want:     marker + "[user]\n\tname = Me\n\temail = me@example.com\n[core]\n\tautocrlf = \"input\"\n",
+			changed:  []string{"user.email"},
+		},
+		{
+			name:     "unchanged",
+			existing: marker + managed,
+			managed:  managed,
+			want:     marker + managed,
+		},
+		{
+			name: "removes keys no longer managed",
+			existing: gitOwnedMarker + ` "core.autocrlf" "user.email" "commit.gpgsign" "includeif.gitdir:~/my work/.path"` + "\n" +
+				managed + "[commit]\n\tgpgsign = true\n\tverbose = true\n[includeIf \"gitdir:~/my work/\"]\n\tpath = ~/work.gitconfig\n\n[alias]\n\tst = status\n",
+			managed: managed,
+			want:    marker + managed + "[commit]\n\tverbose = true\n\n[alias]\n\tst = status\n",
+			changed: []string{"commit.gpgsign", "includeif.gitdir:~/my work/.path"},
+		},
+		{
+			name:     "adds a subsection",
+			existing: marker + managed,
+			managed:  managed + "[includeIf \"gitdir:~/work/\"]\n\tpath = ~/work.gitconfig\n",
+			want: gitOwnedMarker + ` "core.autocrlf" "user.email" "includeif.gitdir:~/work/.path"` + "\n" +
+				managed + "[includeIf \"gitdir:~/work/\"]\n\tpath = ~/work.gitconfig\n",
+			changed: []string{"includeif.gitdir:~/work/.path"},
+		},
+		{
+			name:     "multi-valued key",
+			existing: "[credential]\n\thelper = cache\n[user]\n\tname = Me\n",
+			managed:  "[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n",
+			want:     gitOwnedMarker + ` "credential.helper"` + "\n" + "[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n[user]\n\tname = Me\n",
+			changed:  []string{"credential.helper"},
+		},
+		{
+			name:     "multi-valued key replaces every occurrence",
+			existing: "[credential]\n  helper = a\n\tusername = me\n  Helper = b\n[credential]\n\thelper = c\n",
+			managed:  "[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n",
+			want:     gitOwnedMarker + ` "credential.helper"` + "\n" + "[credential]\n  helper = \"\"\n  helper = osxkeychain\n\tusername = me\n[credential]\n",
+			changed:  []string{"credential.helper"},
+		},
+		{
+			name:     "multi-valued key in a new section",
+			existing: "[user]\n\tname = Me\n",
+			managed:  "[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n",
+			want:     gitOwnedMarker + ` "credential.helper"` + "\n" + "[user]\n\tname = Me\n[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n",
+			changed:  []string{"credential.helper"},
+		},
+		{
+			name:     "multi-valued key unchanged",
+			existing: gitOwnedMarker + ` "credential.helper"` + "\n" + "[credential]\n\thelper =\n\thelper = osxkeychain ; mine\n",
+			managed:  "[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n",
+			want:     gitOwnedMarker + ` "credential.helper"` + "\n" + "[credential]\n\thelper =\n\thelper = osxkeychain ; mine\n",
+		},
+		{
+			name:     "multi-valued key no longer managed",
+			existing: gitOwnedMarker + ` "credential.helper"` + "\n" + "[credential]\n\thelper = \"\"\n\thelper = osxkeychain\n",
+			managed:  managed,
+			want:     marker + managed,
+			changed:  []string{"credential.helper", "core.autocrlf", "user.email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := mergeGitConfig([]byte(tt.existing), []byte(tt.managed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			again, _, _ := mergeGitConfig(got, []byte(tt.managed))
+			if string(again) != string(got) {
+				t.Errorf("second merge changed the file:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestUnwrapBlock(t *testing.T) {
+	in := "[a]\n\tx = 1\n# >>> henrik-os git >>>\n[core]\n\tautocrlf = input\n# <<< henrik-os git <<<\n"
+	want := "[a]\n\tx = 1\n[core]\n\tautocrlf = input\n"
+	if got := string(unwrapBlock([]byte(in), "git")); got != want {
+		t.Errorf("unwrapBlock = %q, want %q", got, want)
+	}
+}
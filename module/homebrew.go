@@ -1,10 +1,15 @@
 package module
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os/exec"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 func init() {
@@ -17,6 +22,8 @@ func (h *Homebrew) Name() string         { return "Homebrew + Packages" }
 func (h *Homebrew) ID() string           { return "homebrew" }
 func (h *Homebrew) Description() string  { return "Install Homebrew, formulae, casks, and fonts" }
 func (h *Homebrew) Dependencies() []string { return []string{"xcode"} }
+func (h *Homebrew) Tags() []string         { return []string{"system"} }
+func (h *Homebrew) Platforms() []string    { return []string{"darwin"} }
 
 var formulae = []string{
 	"fish", "neovim", "starship", "git", "gh",
@@ -37,12 +44,105 @@ var fonts = []string{
 	"font-symbols-only-nerd-font",
 }
 
+// brewfiles are extra Brewfiles whose entries are installed alongside the
+// built-in package lists.
+var brewfiles []string
+
+// SetBrewfiles sets the Brewfiles used as additional package sources.
+func SetBrewfiles(paths []string) {
+	brewfiles = paths
+}
+
+// EffectiveBrewfile returns the built-in package set merged with every
+// configured Brewfile.
+func EffectiveBrewfile() (*Brewfile, error) {
+	bf := &Brewfile{}
+	bf.Merge(&Brewfile{Brews: formulae, Casks: append(append([]string{}, casks...), fonts...)})
+	for _, path := range brewfiles {
+		extra, err := ReadBrewfile(path)
+		if err != nil {
+			return nil, err
+		}
+		bf.Merge(extra)
+	}
+	return bf, nil
+}
+
+const brewInstallScript = `$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)`
+
+func (h *Homebrew) Commands() []string {
+	cmds := []string{`/bin/bash -c "` + brewInstallScript + `" (if missing)`}
+	bf, err := EffectiveBrewfile()
+	if err != nil {
+		return cmds
+	}
+	for _, tap := range bf.Taps {
+		cmds = append(cmds, "brew tap "+tap)
+	}
+	if len(bf.Brews) > 0 {
+		cmds = append(cmds, "brew install --formula "+strings.Join(bf.Brews, " "))
+	}
+	if len(bf.Casks) > 0 {
+		cmds = append(cmds, "brew install --cask "+strings.Join(bf.Casks, " "))
+	}
+	for _, app := range bf.Mas {
+		cmds = append(cmds, fmt.Sprintf("mas install %d", app.ID))
+	}
+	return cmds
+}
+
+// appSteps are the sign-in steps for casks, shown when the cask is part of
+// the effective Brewfile.
+var appSteps = []struct {
+	casks []string
+	step  Step
+}{
+	{[]string{"orbstack", "docker"}, Step{Title: "Docker", Action: "Open OrbStack or Docker Desktop → sign in", Done: succeeds("docker", "info")}},
+	{[]string{"raycast"}, Step{Title: "Raycast", Action: "Open Raycast → set as Spotlight replacement (disable Spotlight: System Settings → Keyboard → Shortcuts)", Done: appData("Library/Application Support/com.raycast.macos")}},
+	{[]string{"slack"}, Step{Title: "Slack", Action: "Sign in", Done: appData("Library/Application Support/Slack")}},
+	{[]string{"discord"}, Step{Title: "Discord", Action: "Sign in", Done: appData("Library/Application Support/discord")}},
+	{[]string{"figma"}, Step{Title: "Figma", Action: "Sign in", Done: appData("Library/Application Support/Figma")}},
+	{[]string{"claude"}, Step{Title: "Claude Desktop", Action: "Open Claude app → sign in", Done: appData("Library/Application Support/Claude")}},
+}
+
+// Check reports whether Homebrew is installed.
+func (h *Homebrew) Check() error {
+	if brewPath() == "" {
+		return fmt.Errorf("brew not found")
+	}
+	return nil
+}
+
+func (h *Homebrew) Steps() []Step {
+	bf, err := EffectiveBrewfile()
+	if err != nil {
+		return nil
+	}
+	var steps []Step
+	for _, a := range appSteps {
+		for _, cask := range a.casks {
+			if slices.Contains(bf.Casks, cask) {
+				steps = append(steps, a.step)
+				break
+			}
+		}
+	}
+	return steps
+}
+
+func (h *Homebrew) Preflight() []Result {
+	results := []Result{needDisk(10)}
+	if brewPath() == "" {
+		results = append(results, needHost("https://raw.githubusercontent.com"))
+	}
+	return results
+}
+
 func (h *Homebrew) Install(w io.Writer) error {
 	// Install Homebrew if missing
-	if _, err := exec.LookPath("brew"); err != nil {
+	if brewPath() == "" {
 		fmt.Fprintln(w, "  Installing Homebrew...")
-		cmd := exec.Command("/bin/bash", "-c",
-			`$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)`)
+		cmd := exec.Command("/bin/bash", "-c", brewInstallScript)
 		cmd.Stdout = w
 		cmd.Stderr = w
 		if err := cmd.Run(); err != nil {
@@ -52,41 +152,251 @@ func (h *Homebrew) Install(w io.Writer) error {
 		fmt.Fprintln(w, "  Homebrew already installed")
 	}
 
-	brewInstall(w, "formulae", formulae, false)
-	brewInstall(w, "casks", casks, true)
-	brewInstall(w, "fonts", fonts, true)
+	bf, err := EffectiveBrewfile()
+	if err != nil {
+		return err
+	}
+
+	brewTap(w, bf.Taps)
+	brewInstall(w, "formulae", bf.Brews, false)
+	brewInstall(w, "casks", bf.Casks, true)
+	masInstall(w, bf.Mas)
 	return nil
 }
 
+func brewTap(w io.Writer, taps []string) {
+	if len(taps) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  Adding taps...")
+	out, _ := exec.Command(brewPath(), "tap").Output()
+	tapped := strings.Fields(string(out))
+	for _, tap := range taps {
+		if slices.Contains(tapped, tap) {
+			fmt.Fprintf(w, "    %-25s already tapped\n", tap)
+			continue
+		}
+		if err := exec.Command(brewPath(), "tap", tap).Run(); err != nil {
+			fmt.Fprintf(w, "    %-25s failed (non-fatal)\n", tap)
+		} else {
+			fmt.Fprintf(w, "    %-25s tapped\n", tap)
+		}
+	}
+}
+
+func masInstall(w io.Writer, apps []MasApp) {
+	if len(apps) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  Installing App Store apps...")
+	if _, err := exec.LookPath("mas"); err != nil {
+		fmt.Fprintln(w, "    mas not installed - skipping (add brew \"mas\" to a Brewfile)")
+		return
+	}
+	out, _ := exec.Command("mas", "list").Output()
+	installed := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			installed[fields[0]] = true
+		}
+	}
+	for _, app := range apps {
+		id := strconv.Itoa(app.ID)
+		if installed[id] {
+			fmt.Fprintf(w, "    %-25s already installed\n", app.Name)
+			continue
+		}
+		if err := exec.Command("mas", "install", id).Run(); err != nil {
+			fmt.Fprintf(w, "    %-25s failed (non-fatal)\n", app.Name)
+		} else {
+			fmt.Fprintf(w, "    %-25s installed\n", app.Name)
+		}
+	}
+}
+
+// brewInstall installs the missing packages of one category with a single
+// brew invocation. Installed state comes from one inventory query before
+// and after, and brew's warnings and errors are attributed back to the
+// package they mention.
 func brewInstall(w io.Writer, label string, packages []string, cask bool) {
 	fmt.Fprintf(w, "  Installing %s...\n", label)
+	brew := &Brew{Path: brewPath(), Cask: cask}
+	inventory, _ := brew.Inventory()
+
+	var missing []string
 	for _, pkg := range packages {
-		if brewInstalled(pkg, cask) {
+		if inInventory(inventory, pkg) {
 			fmt.Fprintf(w, "    %-25s already installed\n", pkg)
-			continue
+		} else {
+			missing = append(missing, pkg)
 		}
-		args := []string{"install"}
-		if cask {
-			args = append(args, "--cask")
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	var out strings.Builder
+	batchErr := brew.Install(&out, missing...)
+	messages := brewMessages(out.String(), missing)
+	inventory, err := brew.Inventory()
+	if err != nil {
+		inventory = make(map[string]string)
+	}
+
+	for _, pkg := range missing {
+		if !inInventory(inventory, pkg) && batchErr != nil {
+			// brew aborts the whole batch on some errors (e.g. an unknown
+			// name), so give anything left over an install of its own.
+			var single strings.Builder
+			if err := brew.Install(&single, pkg); err == nil {
+				inventory[path.Base(pkg)] = ""
+			}
+			messages[pkg] = brewMessages(single.String(), []string{pkg})[pkg]
 		}
-		args = append(args, pkg)
-		if err := exec.Command("brew", args...).Run(); err != nil {
-			fmt.Fprintf(w, "    %-25s failed (non-fatal)\n", pkg)
-		} else {
+
+		if inInventory(inventory, pkg) {
 			fmt.Fprintf(w, "    %-25s installed\n", pkg)
+		} else {
+			fmt.Fprintf(w, "    %-25s failed (non-fatal)\n", pkg)
+		}
+		for _, msg := range messages[pkg] {
+			fmt.Fprintf(w, "      %s\n", msg)
+		}
+	}
+}
+
+// brewMessages picks the Warning: and Error: lines out of brew output and
+// groups them by the package they mention.
+func brewMessages(output string, pkgs []string) map[string][]string {
+	messages := make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "Warning:") && !strings.HasPrefix(line, "Error:") {
+			continue
+		}
+		for _, pkg := range pkgs {
+			if mentionsPackage(line, path.Base(pkg)) {
+				messages[pkg] = append(messages[pkg], line)
+			}
+		}
+	}
+	return messages
+}
+
+// mentionsPackage reports whether name appears as a whole word in line.
+func mentionsPackage(line, name string) bool {
+	words := strings.FieldsFunc(line, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-@.+_", r)
+	})
+	for _, word := range words {
+		if strings.TrimSuffix(word, ".") == name {
+			return true
 		}
 	}
+	return false
+}
+
+type brewOutdated struct {
+	Formulae []brewOutdatedEntry `json:"formulae"`
+	Casks    []brewOutdatedEntry `json:"casks"`
+}
+
+type brewOutdatedEntry struct {
+	Name              string          `json:"name"`
+	InstalledVersions json.RawMessage `json:"installed_versions"`
+	CurrentVersion    string          `json:"current_version"`
+}
+
+// installed returns the installed version, which brew reports as a list for
+// formulae and as a plain string for some casks.
+func (e brewOutdatedEntry) installed() string {
+	var list []string
+	if json.Unmarshal(e.InstalledVersions, &list) == nil && len(list) > 0 {
+		return list[len(list)-1]
+	}
+	var single string
+	_ = json.Unmarshal(e.InstalledVersions, &single)
+	return single
 }
 
-func brewInstalled(pkg string, cask bool) bool {
-	args := []string{"list"}
-	if cask {
-		args = append(args, "--cask")
+// outdated returns the managed formulae and casks that brew reports as
+// outdated.
+func (h *Homebrew) outdated() (formulae, casks []brewOutdatedEntry, err error) {
+	if brewPath() == "" {
+		return nil, nil, fmt.Errorf("homebrew is not installed")
+	}
+	bf, err := EffectiveBrewfile()
+	if err != nil {
+		return nil, nil, err
 	}
-	args = append(args, pkg)
-	out, err := exec.Command("brew", args...).CombinedOutput()
+	out, err := exec.Command(brewPath(), "outdated", "--json=v2").Output()
 	if err != nil {
+		return nil, nil, fmt.Errorf("brew outdated: %w", err)
+	}
+	var result brewOutdated
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, nil, fmt.Errorf("parsing brew outdated: %w", err)
+	}
+
+	managed := func(list []string, name string) bool {
+		for _, pkg := range list {
+			if path.Base(pkg) == name {
+				return true
+			}
+		}
 		return false
 	}
-	return len(strings.TrimSpace(string(out))) > 0
+	for _, e := range result.Formulae {
+		if managed(bf.Brews, e.Name) {
+			formulae = append(formulae, e)
+		}
+	}
+	for _, e := range result.Casks {
+		if managed(bf.Casks, e.Name) {
+			casks = append(casks, e)
+		}
+	}
+	return formulae, casks, nil
+}
+
+func (h *Homebrew) PlanUpdate() ([]string, error) {
+	formulae, casks, err := h.outdated()
+	if err != nil {
+		return nil, err
+	}
+	var plan []string
+	for _, e := range formulae {
+		plan = append(plan, fmt.Sprintf("%s %s → %s", e.Name, e.installed(), e.CurrentVersion))
+	}
+	for _, e := range casks {
+		plan = append(plan, fmt.Sprintf("%s (cask) %s → %s", e.Name, e.installed(), e.CurrentVersion))
+	}
+	return plan, nil
+}
+
+func (h *Homebrew) Update(w io.Writer) error {
+	formulae, casks, err := h.outdated()
+	if err != nil {
+		return err
+	}
+	names := func(entries []brewOutdatedEntry) []string {
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.Name)
+		}
+		return result
+	}
+	if len(formulae) > 0 {
+		fmt.Fprintln(w, "  Upgrading formulae...")
+		if err := (&Brew{Path: brewPath()}).Upgrade(w, names(formulae)...); err != nil {
+			return fmt.Errorf("upgrading formulae: %w", err)
+		}
+	}
+	if len(casks) > 0 {
+		fmt.Fprintln(w, "  Upgrading casks...")
+		if err := (&Brew{Path: brewPath(), Cask: true}).Upgrade(w, names(casks)...); err != nil {
+			return fmt.Errorf("upgrading casks: %w", err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,121 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBrew installs a brew that keeps its formulae in a file and, like
+// the real one, aborts a whole install when any name is unknown.
+func fakeBrew(t *testing.T, installed ...string) *fakeBin {
+	t.Helper()
+	bin := newFakeBin(t)
+	state := filepath.Join(bin.dir, "installed")
+	bin.add(t, "brew", `state="`+state+`"
+touch "$state"
+case "$1" in
+list)
+	while read pkg; do echo "$pkg 1.0"; done < "$state"
+	;;
+install)
+	shift
+	for pkg in "$@"; do
+		case "$pkg" in
+		bad*) echo "Error: No available formula with the name \"$pkg\"." >&2; exit 1 ;;
+		esac
+	done
+	for pkg in "$@"; do
+		echo "==> Pouring $pkg--1.0.bottle.tar.gz"
+		case "$pkg" in
+		*/*) echo "$pkg" | sed 's|.*/||' >> "$state" ;;
+		*) echo "$pkg" >> "$state" ;;
+		esac
+	done
+	echo "Warning: $1 needs a newer Xcode, linking anyway"
+	;;
+esac`)
+	var seed string
+	for _, pkg := range installed {
+		seed += pkg + "\n"
+	}
+	if err := os.WriteFile(state, []byte(seed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return bin
+}
+
+func TestBrewInstallBatch(t *testing.T) {
+	bin := fakeBrew(t, "git")
+
+	var out strings.Builder
+	brewInstall(&out, "formulae", []string{"git", "fd", "me/tap/tool"}, false)
+
+	want := []string{
+		"brew list --versions --formula",
+		"brew install fd me/tap/tool",
+		"brew list --versions --formula",
+	}
+	if got := bin.calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("brew calls =\n%q\nwant\n%q", got, want)
+	}
+	for _, line := range []string{
+		"git                       already installed",
+		"fd                        installed\n      Warning: fd needs a newer Xcode",
+		"me/tap/tool               installed",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output does not contain %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestBrewInstallFallback(t *testing.T) {
+	bin := fakeBrew(t)
+
+	var out strings.Builder
+	brewInstall(&out, "formulae", []string{"fd", "badname", "ripgrep"}, false)
+
+	// The failed batch installed nothing, so each package is retried alone.
+	want := []string{
+		"brew list --versions --formula",
+		"brew install fd badname ripgrep",
+		"brew list --versions --formula",
+		"brew install fd",
+		"brew install badname",
+		"brew install ripgrep",
+	}
+	if got := bin.calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("brew calls =\n%q\nwant\n%q", got, want)
+	}
+	for _, line := range []string{
+		"fd                        installed",
+		"badname                   failed (non-fatal)\n      Error: No available formula with the name \"badname\".",
+		"ripgrep                   installed",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("output does not contain %q:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestBrewMessages(t *testing.T) {
+	output := `==> Fetching fd
+Warning: fd 9.0.0 is already installed and up-to-date.
+Error: fd-find is not a formula
+Warning: Treating tool as a formula.
+Error: The following formula cannot be installed: node@20
+==> Pouring ripgrep
+`
+	got := brewMessages(output, []string{"fd", "me/tap/tool", "node@20", "ripgrep"})
+	want := map[string][]string{
+		"fd":          {"Warning: fd 9.0.0 is already installed and up-to-date."},
+		"me/tap/tool": {"Warning: Treating tool as a formula."},
+		"node@20":     {"Error: The following formula cannot be installed: node@20"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("brewMessages =\n%q\nwant\n%q", got, want)
+	}
+}
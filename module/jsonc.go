@@ -0,0 +1,225 @@
+package module
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// stripJSONC turns JSON with comments and trailing commas, as used by VS
+// Code, into plain JSON. Comments are replaced by spaces so offsets in
+// error messages still point into the original text.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			start := i
+			for i++; i < len(data) && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			end := min(i+1, len(data))
+			out = append(out, data[start:end]...)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for ; i < len(data) && data[i] != '\n'; i++ {
+				out = append(out, ' ')
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			out = append(out, ' ', ' ')
+			for i += 2; i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/'); i++ {
+				if data[i] == '\n' {
+					out = append(out, '\n')
+				} else {
+					out = append(out, ' ')
+				}
+			}
+			if i < len(data) {
+				out = append(out, ' ', ' ')
+				i++
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return stripTrailingCommas(out)
+}
+
+// stripTrailingCommas blanks commas that are followed only by whitespace
+// before a closing bracket. data must not contain comments.
+func stripTrailingCommas(data []byte) []byte {
+	inString := false
+	last := -1 // index of the last comma outside a string
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			last = -1
+		case ',':
+			last = i
+		case '}', ']':
+			if last >= 0 {
+				data[last] = ' '
+			}
+			last = -1
+		case ' ', '\t', '\n', '\r':
+		default:
+			last = -1
+		}
+	}
+	return data
+}
+
+// jsoncMember is a top-level member of a JSONC object. Offsets index the
+// original text.
+type jsoncMember struct {
+	key        string
+	keyStart   int
+	valueStart int
+	valueEnd   int
+}
+
+// jsoncMembers returns the members of the top-level object in data and the
+// offset of its closing brace.
+func jsoncMembers(data []byte) ([]jsoncMember, int, error) {
+	plain := stripJSONC(append([]byte(nil), data...))
+	dec := json.NewDecoder(bytes.NewReader(plain))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, 0, fmt.Errorf("expected a JSON object")
+	}
+
+	var members []jsoncMember
+	for dec.More() {
+		// The key starts at the first quote after the previous member;
+		// comments are blanked in plain, so only spaces and a comma come
+		// before it.
+		start := int(dec.InputOffset())
+		for start < len(plain) && plain[start] != '"' {
+			start++
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, 0, err
+		}
+		m := jsoncMember{key: tok.(string), keyStart: start}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, 0, err
+		}
+		m.valueEnd = int(dec.InputOffset())
+		m.valueStart = m.valueEnd - len(value)
+		members = append(members, m)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, 0, err
+	}
+	return members, int(dec.InputOffset()) - 1, nil
+}
+
+// jsoncEqual reports whether two JSONC values are the same, ignoring
+// formatting, comments and key order.
+func jsoncEqual(a, b []byte) bool {
+	var va, vb any
+	if json.Unmarshal(stripJSONC(append([]byte(nil), a...)), &va) != nil ||
+		json.Unmarshal(stripJSONC(append([]byte(nil), b...)), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// mergeJSONC applies the top-level keys of managed onto the existing JSONC
+// object. Keys listed in defaults are only added when absent; every other
+// key is set to the managed value. Unmanaged keys, comments and formatting
+// in existing are kept.
+func mergeJSONC(existing, managed []byte, defaults []string) ([]byte, []string, error) {
+	if len(bytes.TrimSpace(existing)) == 0 {
+		want, _, err := jsoncMembers(managed)
+		if err != nil {
+			return nil, nil, err
+		}
+		var changed []string
+		for _, m := range want {
+			changed = append(changed, m.key)
+		}
+		return managed, changed, nil
+	}
+
+	have, closing, err := jsoncMembers(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+	want, _, err := jsoncMembers(managed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("managed settings: %w", err)
+	}
+	index := make(map[string]jsoncMember)
+	for _, m := range have {
+		index[m.key] = m
+	}
+
+	// Indent added keys like the first existing one, unless it shares its
+	// line with something else, such as the opening brace.
+	indent := "  "
+	if len(have) > 0 {
+		line := bytes.LastIndexByte(existing[:have[0].keyStart], '\n')
+		if prefix := existing[line+1 : have[0].keyStart]; len(bytes.Trim(prefix, " \t")) == 0 {
+			indent = string(prefix)
+		}
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	var added []string
+	var changed []string
+	for _, m := range want {
+		value := managed[m.valueStart:m.valueEnd]
+		cur, ok := index[m.key]
+		switch {
+		case !ok:
+			added = append(added, indent+string(managed[m.keyStart:m.valueEnd]))
+		case slices.Contains(defaults, m.key) || jsoncEqual(existing[cur.valueStart:cur.valueEnd], value):
+			continue
+		default:
+			edits = append(edits, edit{cur.valueStart, cur.valueEnd, string(value)})
+		}
+		changed = append(changed, m.key)
+	}
+
+	if len(added) > 0 {
+		if len(have) > 0 {
+			end := have[len(have)-1].valueEnd
+			edits = append(edits, edit{end, end, ",\n" + strings.Join(added, ",\n")})
+		} else {
+			edits = append(edits, edit{closing, closing, "\n" + strings.Join(added, ",\n") + "\n"})
+		}
+	}
+
+	slices.SortFunc(edits, func(a, b edit) int { return a.start - b.start })
+	out := append([]byte(nil), existing...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	return out, changed, nil
+}
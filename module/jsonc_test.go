@@ -0,0 +1,107 @@
+package module
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeJSONC(t *testing.T) {
+	managed := `{
+  // Editor
+  "editor.fontSize": 14,
+  "new.key": 1,
+}`
+	tests := []struct {
+		name     string
+		existing string
+		defaults []string
+		want     string
+		changed  []string
+	}{
+		{
+			name:     "missing file",
+			existing: "",
+			want:     managed,
+			changed:  []string{"editor.fontSize", "new.key"},
+		},
+		{
+			name:     "compact",
+			existing: `{"editor.fontSize": 12}`,
+			want:     "{\"editor.fontSize\": 14,\n  \"new.key\": 1}",
+			changed:  []string{"editor.fontSize", "new.key"},
+		},
+		{
+			name:     "empty object",
+			existing: `{}`,
+			want:     "{\n  \"editor.fontSize\": 14,\n  \"new.key\": 1\n}",
+			changed:  []string{"editor.fontSize", "new.key"},
+		},
+		{
+			name:     "trailing comma",
+			existing: "{\n    \"a\": true,\n}\n",
+			want:     "{\n    \"a\": true,\n    \"editor.fontSize\": 14,\n    \"new.key\": 1,\n}\n",
+			changed:  []string{"editor.fontSize", "new.key"},
+		},
+		{
+			name:     "comments kept",
+			existing: "{\n  // mine\n  \"editor.fontSize\": 18, /* big */\n  \"new.key\": 1 // same\n}\n",
+			want:     "{\n  // mine\n  \"editor.fontSize\": 14, /* big */\n  \"new.key\": 1 // same\n}\n",
+			changed:  []string{"editor.fontSize"},
+		},
+		{
+			name:     "default kept",
+			existing: "{\n  \"editor.fontSize\": 18,\n  \"new.key\": 1\n}\n",
+			defaults: []string{"editor.fontSize"},
+			want:     "{\n  \"editor.fontSize\": 18,\n  \"new.key\": 1\n}\n",
+		},
+		{
+			name:     "escaped key",
+			existing: "{\n  \"a\\\"b\": {\"c\": [1, 2,],},\n  \"editor.fontSize\": 14\n}",
+			want:     "{\n  \"a\\\"b\": {\"c\": [1, 2,],},\n  \"editor.fontSize\": 14,\n  \"new.key\": 1\n}",
+			changed:  []string{"new.key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := mergeJSONC([]byte(tt.existing), []byte(managed), tt.defaults)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("merged:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			var v map[string]any
+			if err := json.Unmarshal(stripJSONC(got), &v); err != nil {
+				t.Errorf("merged settings are not valid JSONC: %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeJSONCInvalid(t *testing.T) {
+	for _, existing := range []string{"{ broken", "[1, 2]", `{"a": 1`} {
+		if _, _, err := mergeJSONC([]byte(existing), []byte(`{"a": 2}`), nil); err == nil {
+			t.Errorf("mergeJSONC(%q) succeeded", existing)
+		}
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	in := "{\n  // c\n  \"a\": \"// not a comment\", /* x */\n  \"b\": [1,],\n}"
+	out := stripJSONC([]byte(in))
+	if len(out) != len(in) {
+		t.Errorf("length changed: %d → %d", len(in), len(out))
+	}
+	var v map[string]any
+	if err := json.Unmarshal(out, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["a"] != "// not a comment" || !strings.Contains(string(out), "[1 ]") {
+		t.Errorf("unexpected result %s", out)
+	}
+}
@@ -0,0 +1,151 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkRoot is the configs directory of a local checkout. When set, managed
+// files are deployed as symlinks into it instead of copies.
+var linkRoot string
+
+// SetLinkRoot enables link mode. path may point at the repository root or
+// directly at its configs directory.
+func SetLinkRoot(path string) error {
+	abs, err := filepath.Abs(ExpandHome(path))
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", path, err)
+	}
+	if info, err := os.Stat(filepath.Join(abs, "configs")); err == nil && info.IsDir() {
+		abs = filepath.Join(abs, "configs")
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("link root: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("link root %s is not a directory", abs)
+	}
+	linkRoot = abs
+
+	// Remember the checkout so unlink and status know which links are
+	// henrik-os's when run without it.
+	if err := os.MkdirAll(ConfigDir(), 0o755); err != nil {
+		return fmt.Errorf("recording link root: %w", err)
+	}
+	if err := os.WriteFile(linkRootState(), []byte(abs+"\n"), 0o644); err != nil {
+		return fmt.Errorf("recording link root: %w", err)
+	}
+	return nil
+}
+
+func linkRootState() string {
+	return filepath.Join(ConfigDir(), "link-root")
+}
+
+// checkoutRoot returns the configs directory links point into: the one in
+// use, or else the one last linked. It returns "" when neither is known.
+func checkoutRoot() string {
+	if linkRoot != "" {
+		return linkRoot
+	}
+	data, err := os.ReadFile(linkRootState())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// LinkRoot returns the configs directory used in link mode, or "" when
+// files are copied.
+func LinkRoot() string {
+	return linkRoot
+}
+
+// LinkFile replaces the destination of f with a symlink to its source in
+// the checkout. Existing files are backed up to <path>.bak first.
+func LinkFile(w io.Writer, f File) error {
+	if linkRoot == "" {
+		return fmt.Errorf("link mode is not enabled")
+	}
+	if f.Block != "" || f.Merge != nil || f.FS != nil {
+		fmt.Fprintf(w, "  Skipping %s (not linkable)\n", f.Dest)
+		return nil
+	}
+	target := filepath.Join(linkRoot, f.Src)
+	if _, err := os.Stat(target); err != nil {
+		return fmt.Errorf("%s not found in checkout %s", f.Src, linkRoot)
+	}
+
+	if state, _ := Status(f); state == StateLinked {
+		fmt.Fprintf(w, "  Already linked %s\n", f.Dest)
+		return nil
+	}
+
+	dir := filepath.Dir(f.Dest)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating directory %s: %w", dir, err)
+	}
+
+	if _, err := os.Lstat(f.Dest); err == nil {
+		// Back up the content (following links) so foreign links are kept too.
+		if _, err := os.Stat(f.Dest); err == nil {
+			bak := f.Dest + ".bak"
+			if err := copyFile(f.Dest, bak); err != nil {
+				return fmt.Errorf("backing up %s: %w", f.Dest, err)
+			}
+			fmt.Fprintf(w, "  Backed up %s → %s\n", f.Dest, bak)
+		}
+		if err := os.Remove(f.Dest); err != nil {
+			return fmt.Errorf("removing %s: %w", f.Dest, err)
+		}
+	}
+
+	if err := os.Symlink(target, f.Dest); err != nil {
+		return fmt.Errorf("linking %s: %w", f.Dest, err)
+	}
+	fmt.Fprintf(w, "  Linked %s → %s\n", f.Dest, target)
+	return nil
+}
+
+// UnlinkFile converts a symlink into the checkout back into a regular
+// file. The content comes from the link target so local edits in the
+// checkout are kept; broken links fall back to the source config. Links
+// pointing anywhere else are not henrik-os's and are left alone.
+func UnlinkFile(w io.Writer, f File) error {
+	info, err := os.Lstat(f.Dest)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	target, err := os.Readlink(f.Dest)
+	if err != nil {
+		return fmt.Errorf("reading link %s: %w", f.Dest, err)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(f.Dest), target)
+	}
+	if !isLinkTarget(target, f.Src) {
+		fmt.Fprintf(w, "  Skipping %s (links to %s, outside the checkout)\n", f.Dest, target)
+		return nil
+	}
+
+	data, err := os.ReadFile(f.Dest)
+	if err != nil {
+		data, err = f.source().ReadFile(f.Src)
+		if err != nil {
+			return fmt.Errorf("reading source %s: %w", f.Src, err)
+		}
+	}
+
+	if err := os.Remove(f.Dest); err != nil {
+		return fmt.Errorf("removing %s: %w", f.Dest, err)
+	}
+	if err := os.WriteFile(f.Dest, data, f.Perm); err != nil {
+		return fmt.Errorf("writing %s: %w", f.Dest, err)
+	}
+	fmt.Fprintf(w, "  Copied %s\n", f.Dest)
+	return nil
+}
@@ -0,0 +1,62 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinkOwnership(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	prev := linkRoot
+	linkRoot = ""
+	t.Cleanup(func() { linkRoot = prev })
+
+	// Two checkouts with the same layout.
+	checkout, other := t.TempDir(), t.TempDir()
+	for _, dir := range []string{checkout, other} {
+		os.MkdirAll(filepath.Join(dir, "configs/fish"), 0o755)
+		os.WriteFile(filepath.Join(dir, "configs/fish/config.fish"), []byte("# fish\n"), 0o644)
+	}
+	f := File{Src: "fish/config.fish", Dest: filepath.Join(home, "config.fish"), Perm: 0o644}
+	link := func(dir string) {
+		t.Helper()
+		os.Remove(f.Dest)
+		if err := os.Symlink(filepath.Join(dir, "configs", f.Src), f.Dest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without a known checkout no link is ours.
+	link(checkout)
+	if state, _ := Status(f); state != StateForeign {
+		t.Errorf("status with unknown checkout = %s, want foreign", state)
+	}
+
+	// The checkout is remembered for later runs without --link.
+	if err := SetLinkRoot(checkout); err != nil {
+		t.Fatal(err)
+	}
+	linkRoot = ""
+	if state, _ := Status(f); state != StateLinked {
+		t.Errorf("status = %s, want linked", state)
+	}
+
+	link(other)
+	if state, _ := Status(f); state != StateForeign {
+		t.Errorf("status of link into another checkout = %s, want foreign", state)
+	}
+	var out strings.Builder
+	if err := UnlinkFile(&out, f); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Lstat(f.Dest); info.Mode()&os.ModeSymlink == 0 {
+		t.Error("unlink replaced a link into another checkout")
+	}
+	if !strings.Contains(out.String(), "outside the checkout") {
+		t.Errorf("output = %q", out.String())
+	}
+}
@@ -3,10 +3,10 @@ package module
 import (
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"strings"
 )
 
 func init() {
@@ -19,12 +19,60 @@ func (m *MacOS) Name() string         { return "macOS Defaults" }
 func (m *MacOS) ID() string           { return "macos" }
 func (m *MacOS) Description() string  { return "Configure macOS keyboard, Finder, Dock, and system preferences" }
 func (m *MacOS) Dependencies() []string { return nil }
+func (m *MacOS) Tags() []string         { return []string{"system"} }
+func (m *MacOS) Platforms() []string    { return []string{"darwin"} }
 
-func (m *MacOS) Install(w io.Writer) error {
-	fmt.Fprintln(w, "  Configuring macOS defaults...")
+const (
+	sudoLocal = "/etc/pam.d/sudo_local"
+	touchID   = "auth       sufficient     pam_tid.so\n"
+)
+
+// NeedsSudo reports whether Touch ID still has to be enabled for sudo.
+func (m *MacOS) NeedsSudo() bool {
+	data, _ := os.ReadFile(sudoLocal)
+	return !touchIDEnabled(data)
+}
+
+// touchIDEnabled reports whether a PAM config loads pam_tid.so. The
+// template macOS ships has the line commented out.
+func touchIDEnabled(pam []byte) bool {
+	for _, line := range strings.Split(string(pam), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") && strings.Contains(line, "pam_tid.so") {
+			return true
+		}
+	}
+	return false
+}
+
+// Preflight warns on macOS releases without /etc/pam.d/sudo_local, where
+// Touch ID for sudo would not survive system updates.
+func (m *MacOS) Preflight() []Result {
+	major, err := macOSMajor()
+	if err != nil {
+		return []Result{{Severity: Warn, Message: err.Error()}}
+	}
+	if major < 14 {
+		return []Result{{Severity: Warn, Message: fmt.Sprintf("macOS %d: Touch ID for sudo needs macOS 14 or later", major)}}
+	}
+	return nil
+}
 
-	// Keyboard
-	defaults := [][]string{
+func (m *MacOS) Verify() error { return verifyPlist(m.Files()[0].Dest) }
+
+func (m *MacOS) Files() []File {
+	return []File{{
+		Src:  "macos/com.henrikkvamme.capslock-escape.plist",
+		Dest: filepath.Join(HomeDir(), "Library/LaunchAgents/com.henrikkvamme.capslock-escape.plist"),
+		Perm: 0o644,
+	}}
+}
+
+// macosDefaults returns the arguments of every `defaults write` the module
+// runs.
+func macosDefaults() [][]string {
+	return [][]string{
+		// Keyboard
 		{"write", "NSGlobalDomain", "KeyRepeat", "-int", "2"},
 		{"write", "NSGlobalDomain", "InitialKeyRepeat", "-int", "15"},
 		{"write", "NSGlobalDomain", "ApplePressAndHoldEnabled", "-bool", "false"},
@@ -44,29 +92,54 @@ func (m *MacOS) Install(w io.Writer) error {
 		{"write", "com.apple.desktopservices", "DSDontWriteNetworkStores", "-bool", "true"},
 		{"write", "com.apple.desktopservices", "DSDontWriteUSBStores", "-bool", "true"},
 	}
+}
 
-	for _, args := range defaults {
+// capsLockMapping remaps Caps Lock to Escape with hidutil.
+const capsLockMapping = `{"UserKeyMapping":[{"HIDKeyboardModifierMappingSrc":0x700000039,"HIDKeyboardModifierMappingDst":0x700000029}]}`
+
+func (m *MacOS) Commands() []string {
+	var cmds []string
+	for _, args := range macosDefaults() {
+		cmds = append(cmds, "defaults "+strings.Join(args, " "))
+	}
+	cmds = append(cmds, "hidutil property --set '"+capsLockMapping+"'")
+	if m.NeedsSudo() {
+		cmds = append(cmds, "sudo tee "+sudoLocal+" (add Touch ID for sudo)")
+	}
+	return append(cmds, "killall Finder", "killall Dock")
+}
+
+func (m *MacOS) Install(w io.Writer) error {
+	fmt.Fprintln(w, "  Configuring macOS defaults...")
+
+	for _, args := range macosDefaults() {
 		_ = exec.Command("defaults", args...).Run()
 	}
 
 	// Caps Lock → Escape
 	fmt.Fprintln(w, "  Mapping Caps Lock to Escape...")
-	_ = exec.Command("hidutil", "property", "--set",
-		`{"UserKeyMapping":[{"HIDKeyboardModifierMappingSrc":0x700000039,"HIDKeyboardModifierMappingDst":0x700000029}]}`).Run()
+	_ = exec.Command("hidutil", "property", "--set", capsLockMapping).Run()
 
 	// Persist with LaunchAgent
-	plistDest := filepath.Join(HomeDir(), "Library/LaunchAgents/com.henrikkvamme.capslock-escape.plist")
-	if err := WriteEmbedded(w, &configs.FS, "macos/com.henrikkvamme.capslock-escape.plist", plistDest, 0o644); err != nil {
+	if err := InstallFiles(w, m); err != nil {
 		return err
 	}
 
 	// Touch ID for sudo
-	fmt.Fprintln(w, "  Enabling Touch ID for sudo...")
-	cmd := exec.Command("sudo", "sh", "-c",
-		`grep -q pam_tid.so /etc/pam.d/sudo_local 2>/dev/null || echo 'auth       sufficient     pam_tid.so' > /etc/pam.d/sudo_local`)
-	cmd.Stdout = w
-	cmd.Stderr = w
-	_ = cmd.Run()
+	if m.NeedsSudo() {
+		// Keep whatever else the file holds; only a missing file starts
+		// empty. Like the other privileged steps it is skipped, not fatal,
+		// when it cannot be done.
+		existing, err := os.ReadFile(sudoLocal)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(w, "  Skipping Touch ID for sudo: reading %s: %v\n", sudoLocal, err)
+		} else {
+			fmt.Fprintln(w, "  Enabling Touch ID for sudo...")
+			if err := SudoWriteFile(w, sudoLocal, RenderBlock(existing, m.ID(), touchID)); err != nil {
+				fmt.Fprintf(w, "  Skipping Touch ID for sudo: %v\n", err)
+			}
+		}
+	}
 
 	// Restart affected apps
 	_ = exec.Command("killall", "Finder").Run()
@@ -0,0 +1,43 @@
+package module
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTouchIDEnabled(t *testing.T) {
+	const template = "# sudo_local: local config file which survives system update\n#auth       sufficient     pam_tid.so\n"
+	tests := []struct {
+		pam  string
+		want bool
+	}{
+		{"", false},
+		{template, false},
+		{"auth sufficient pam_tid.so\n", true},
+		{"auth optional /opt/homebrew/lib/pam/pam_reattach.so\n  auth sufficient pam_tid.so\n", true},
+		{string(RenderBlock([]byte(template), "macos", touchID)), true},
+	}
+	for _, tt := range tests {
+		if got := touchIDEnabled([]byte(tt.pam)); got != tt.want {
+			t.Errorf("touchIDEnabled(%q) = %v, want %v", tt.pam, got, tt.want)
+		}
+	}
+
+	// The template's comments are kept around the block.
+	want := template + "\n# >>> henrik-os macos >>>\n" + touchID + "# <<< henrik-os macos <<<\n"
+	if got := string(RenderBlock([]byte(template), "macos", touchID)); got != want {
+		t.Errorf("sudo_local =\n%q\nwant\n%q", got, want)
+	}
+}
+
+// The dry run only lists the sudo step when it will run.
+func TestMacOSCommandsSudo(t *testing.T) {
+	m := &MacOS{}
+	listed := false
+	for _, c := range m.Commands() {
+		listed = listed || strings.HasPrefix(c, "sudo ")
+	}
+	if listed != m.NeedsSudo() {
+		t.Errorf("sudo step listed = %v, NeedsSudo = %v", listed, m.NeedsSudo())
+	}
+}
@@ -0,0 +1,290 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Manifest is the user configuration read from
+// ~/.config/henrik-os/config.toml:
+//
+//	[ssh]
+//	type = "ed25519"          # ed25519, ecdsa or rsa
+//	passphrase = "none"       # none, prompt or keychain
+//	comment = "me@example.com"
+//
+//	[[identities]]
+//	name = "work"
+//	email = "me@work.com"
+//	host = "github.com"       # default github.com
+//	alias = "github-work"     # Host alias, default <host>-<name>
+//	key = "id_ed25519_work"   # default id_<type>_<name>
+//	gitdir = "~/work/"        # use this email and key for repositories here
+//
+//	[git]
+//	signing = true            # sign commits with the primary SSH key
+//
+//	[vscode]
+//	defaults = ["window.zoomLevel"] # settings only written when absent
+//
+//	[updates]
+//	check = false             # no notice about newer releases
+//
+// Every section is optional; a missing file means the defaults.
+type Manifest struct {
+	SSH        SSHOptions
+	Identities []Identity
+	Git        GitOptions
+	VSCode     VSCodeOptions
+	Updates    UpdateOptions
+}
+
+// UpdateOptions configure the notice about newer henrik-os releases.
+type UpdateOptions struct {
+	DisableCheck bool
+}
+
+// VSCodeOptions configure the vscode module.
+type VSCodeOptions struct {
+	// Defaults lists the settings.json keys that are only set when the
+	// user has not set them. Every other key in the embedded settings is
+	// managed and always set.
+	Defaults []string
+}
+
+// GitOptions configure the git module.
+type GitOptions struct {
+	Signing bool
+}
+
+// SSHOptions are the defaults for generated SSH keys.
+type SSHOptions struct {
+	Type       string
+	Passphrase string
+	Comment    string
+	Key        string // key file name when no identities are configured
+}
+
+// Identity is an account with its own SSH key and Host alias.
+type Identity struct {
+	Name       string
+	Email      string
+	Host       string
+	Alias      string
+	Key        string
+	Type       string
+	Passphrase string
+	Gitdir     string // repositories that commit as this identity
+}
+
+// KeyPath returns the private key path of the identity.
+func (id Identity) KeyPath() string {
+	return filepath.Join(HomeDir(), ".ssh", id.Key)
+}
+
+var manifest = &Manifest{}
+
+// UpdateCheck reports whether henrik-os may look for newer releases.
+func UpdateCheck() bool {
+	return !manifest.Updates.DisableCheck
+}
+
+// ManifestPath returns the location of the user configuration file.
+func ManifestPath() string {
+	return filepath.Join(ConfigDir(), "config.toml")
+}
+
+// LoadManifest reads the user configuration from path. A missing file is
+// not an error.
+func LoadManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	doc, err := decodeTOML(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	m, err := parseManifest(doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	manifest = m
+	return nil
+}
+
+func parseManifest(doc map[string]any) (*Manifest, error) {
+	m := &Manifest{}
+	var errs []string
+	str := func(t map[string]any, key string) string {
+		v, ok := t[key]
+		if !ok {
+			return ""
+		}
+		s, ok := v.(string)
+		if !ok {
+			errs = append(errs, key+" must be a string")
+		}
+		return s
+	}
+
+	boolean := func(t map[string]any, key string) bool {
+		v, ok := t[key]
+		if !ok {
+			return false
+		}
+		b, ok := v.(bool)
+		if !ok {
+			errs = append(errs, key+" must be true or false")
+		}
+		return b
+	}
+
+	git, _ := doc["git"].(map[string]any)
+	m.Git = GitOptions{Signing: boolean(git, "signing")}
+
+	updates, _ := doc["updates"].(map[string]any)
+	if _, ok := updates["check"]; ok {
+		m.Updates.DisableCheck = !boolean(updates, "check")
+	}
+
+	vscode, _ := doc["vscode"].(map[string]any)
+	defaults, err := stringList(vscode["defaults"])
+	if err != nil {
+		errs = append(errs, "vscode.defaults "+err.Error())
+	}
+	m.VSCode = VSCodeOptions{Defaults: defaults}
+
+	ssh, _ := doc["ssh"].(map[string]any)
+	m.SSH = SSHOptions{
+		Type:       str(ssh, "type"),
+		Passphrase: str(ssh, "passphrase"),
+		Comment:    str(ssh, "comment"),
+		Key:        str(ssh, "key"),
+	}
+
+	seen := make(map[string]bool)
+	for i, entry := range tables(doc["identities"]) {
+		t, ok := entry.(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("identities[%d] must be a table", i))
+			continue
+		}
+		id := Identity{
+			Name:       str(t, "name"),
+			Email:      str(t, "email"),
+			Host:       str(t, "host"),
+			Alias:      str(t, "alias"),
+			Key:        str(t, "key"),
+			Type:       str(t, "type"),
+			Passphrase: str(t, "passphrase"),
+			Gitdir:     str(t, "gitdir"),
+		}
+		if id.Name == "" {
+			errs = append(errs, fmt.Sprintf("identities[%d]: name is required", i))
+		} else if seen[id.Name] {
+			errs = append(errs, fmt.Sprintf("identities[%d]: duplicate name %q", i, id.Name))
+		}
+		seen[id.Name] = true
+		m.Identities = append(m.Identities, id)
+	}
+
+	for _, id := range m.sshIdentities() {
+		if err := validateIdentity(id); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return m, nil
+}
+
+func validateIdentity(id Identity) error {
+	if strings.ContainsAny(id.Name, `/\`) {
+		return fmt.Errorf("identity %s: name must not contain slashes", id.Name)
+	}
+	switch id.Type {
+	case "ed25519", "ecdsa", "rsa":
+	default:
+		return fmt.Errorf("identity %s: unsupported key type %q", id.Name, id.Type)
+	}
+	switch id.Passphrase {
+	case "none", "prompt", "keychain":
+	default:
+		return fmt.Errorf("identity %s: passphrase must be none, prompt or keychain", id.Name)
+	}
+	if id.Passphrase == "keychain" && platform != "darwin" {
+		return fmt.Errorf("identity %s: passphrase = \"keychain\" needs the macOS keychain; use prompt", id.Name)
+	}
+	if id.Key == "" || id.Key != filepath.Base(id.Key) || strings.HasPrefix(id.Key, ".") {
+		return fmt.Errorf("identity %s: key must be a file name inside ~/.ssh", id.Name)
+	}
+	if id.Gitdir != "" && id.Email == "" {
+		return fmt.Errorf("identity %s: gitdir needs an email", id.Name)
+	}
+	return nil
+}
+
+// sshIdentities returns the configured identities with defaults filled in.
+// Without any configured identity there is a single default identity for
+// github.com using the [ssh] options.
+func (m *Manifest) sshIdentities() []Identity {
+	keyType := m.SSH.Type
+	if keyType == "" {
+		keyType = "ed25519"
+	}
+	passphrase := m.SSH.Passphrase
+	if passphrase == "" {
+		passphrase = "none"
+	}
+
+	if len(m.Identities) == 0 {
+		key := m.SSH.Key
+		if key == "" {
+			key = "id_" + keyType
+		}
+		return []Identity{{
+			Name:       "default",
+			Email:      m.SSH.Comment,
+			Host:       "github.com",
+			Alias:      "github.com",
+			Key:        key,
+			Type:       keyType,
+			Passphrase: passphrase,
+		}}
+	}
+
+	var result []Identity
+	for _, id := range m.Identities {
+		if id.Type == "" {
+			id.Type = keyType
+		}
+		if id.Passphrase == "" {
+			id.Passphrase = passphrase
+		}
+		if id.Host == "" {
+			id.Host = "github.com"
+		}
+		if id.Alias == "" {
+			id.Alias = id.Host + "-" + id.Name
+		}
+		if id.Key == "" {
+			id.Key = "id_" + id.Type + "_" + id.Name
+		}
+		if id.Email == "" {
+			id.Email = m.SSH.Comment
+		}
+		// A trailing slash makes git match everything below the directory.
+		if id.Gitdir != "" && !strings.HasSuffix(id.Gitdir, "/") {
+			id.Gitdir += "/"
+		}
+		result = append(result, id)
+	}
+	return result
+}
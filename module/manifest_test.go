@@ -0,0 +1,77 @@
+package module
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	doc, err := decodeTOML([]byte(`
+[ssh]
+type = "ecdsa"
+passphrase = "prompt"
+comment = "me@example.com"
+
+[[identities]]
+name = "personal"
+
+[[identities]]
+name = "work"
+email = "me@work.com"
+host = "gitlab.com"
+type = "ed25519"
+gitdir = "~/work"
+
+[git]
+signing = true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseManifest(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Git.Signing {
+		t.Error("git.signing not read")
+	}
+	want := []Identity{
+		{Name: "personal", Email: "me@example.com", Host: "github.com", Alias: "github.com-personal", Key: "id_ecdsa_personal", Type: "ecdsa", Passphrase: "prompt"},
+		{Name: "work", Email: "me@work.com", Host: "gitlab.com", Alias: "gitlab.com-work", Key: "id_ed25519_work", Type: "ed25519", Passphrase: "prompt", Gitdir: "~/work/"},
+	}
+	if got := m.sshIdentities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("identities =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	prev := platform
+	platform = "linux"
+	t.Cleanup(func() { platform = prev })
+
+	tests := []struct {
+		toml string
+		want string
+	}{
+		{"[ssh]\ntype = \"dsa\"", `unsupported key type "dsa"`},
+		{"[ssh]\npassphrase = \"always\"", "passphrase must be none, prompt or keychain"},
+		{"[ssh]\npassphrase = \"keychain\"", "needs the macOS keychain"},
+		{"[ssh]\ntype = 1", "type must be a string"},
+		{"[ssh]\nkey = \"../id_ed25519\"", "key must be a file name inside ~/.ssh"},
+		{"[[identities]]\nemail = \"me@work.com\"", "identities[0]: name is required"},
+		{"[[identities]]\nname = \"work\"\n[[identities]]\nname = \"work\"", `identities[1]: duplicate name "work"`},
+		{"[[identities]]\nname = \"a/b\"", "name must not contain slashes"},
+		{"[[identities]]\nname = \"work\"\ngitdir = \"~/work\"", "gitdir needs an email"},
+		{"[git]\nsigning = \"yes\"", "signing must be true or false"},
+	}
+	for _, tt := range tests {
+		doc, err := decodeTOML([]byte(tt.toml))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.toml, err)
+		}
+		if _, err := parseManifest(doc); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseManifest(%q) = %v, want %q", tt.toml, err, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package module
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -16,6 +17,22 @@ type Module interface {
 	Install(w io.Writer) error
 }
 
+// Checker is implemented by modules that can tell whether they are already
+// in place. Check returns nil when nothing needs installing, and ErrNoCheck
+// when this particular module has no way to tell.
+type Checker interface {
+	Check() error
+}
+
+// ErrNoCheck is returned by Check when a module defines no check.
+var ErrNoCheck = errors.New("no check defined")
+
+// Commander is implemented by modules that run external commands. Commands
+// describes them for display; it does not run anything.
+type Commander interface {
+	Commands() []string
+}
+
 var registry []Module
 
 // Register adds a module to the global registry.
@@ -39,7 +56,9 @@ func ByID(id string) Module {
 }
 
 // Resolve takes a list of module IDs and returns the full list including
-// transitive dependencies, in installation order.
+// transitive dependencies, in installation order. Soft dependencies only
+// affect the order, and conflicting modules are rejected. Resolve has no
+// side effects; installers record their modules with SetInstalling.
 func Resolve(ids []string) ([]Module, error) {
 	needed := make(map[string]bool)
 	var addDeps func(id string) error
@@ -51,12 +70,20 @@ func Resolve(ids []string) ([]Module, error) {
 		if m == nil {
 			return fmt.Errorf("unknown module: %s", id)
 		}
+		if !Supported(m) {
+			return unsupportedError(m)
+		}
+		if err := checkRelations(m); err != nil {
+			return err
+		}
+		// Marked before the dependencies, so a cycle ends here and is
+		// reported by installOrder.
+		needed[id] = true
 		for _, dep := range m.Dependencies() {
 			if err := addDeps(dep); err != nil {
 				return err
 			}
 		}
-		needed[id] = true
 		return nil
 	}
 
@@ -66,14 +93,10 @@ func Resolve(ids []string) ([]Module, error) {
 		}
 	}
 
-	// Return modules in registry order, filtered to those needed.
-	var result []Module
-	for _, m := range registry {
-		if needed[m.ID()] {
-			result = append(result, m)
-		}
+	if err := checkConflicts(needed); err != nil {
+		return nil, err
 	}
-	return result, nil
+	return installOrder(needed)
 }
 
 // BackupAndWrite backs up the existing file (if any) to <path>.bak, then
@@ -122,6 +145,15 @@ func HomeDir() string {
 	return home
 }
 
+// ConfigDir returns the henrik-os configuration directory,
+// $XDG_CONFIG_HOME/henrik-os or ~/.config/henrik-os.
+func ConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "henrik-os")
+	}
+	return filepath.Join(HomeDir(), ".config/henrik-os")
+}
+
 // ExpandHome expands ~ to the user's home directory.
 func ExpandHome(path string) string {
 	if len(path) > 0 && path[0] == '~' {
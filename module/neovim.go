@@ -1,11 +1,17 @@
 package module
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"sort"
+	"strings"
+	"time"
 )
 
 func init() {
@@ -17,9 +23,12 @@ type Neovim struct{}
 func (n *Neovim) Name() string         { return "Neovim + LazyVim" }
 func (n *Neovim) ID() string           { return "neovim" }
 func (n *Neovim) Description() string  { return "Configure Neovim with LazyVim" }
-func (n *Neovim) Dependencies() []string { return []string{"homebrew"} }
+func (n *Neovim) Dependencies() []string { return brewDeps() }
+func (n *Neovim) Tags() []string         { return []string{"editor"} }
 
-func (n *Neovim) Install(w io.Writer) error {
+func (n *Neovim) Packages() []string { return []string{"neovim", "ripgrep", "fd"} }
+
+func (n *Neovim) Files() []File {
 	nvimDir := filepath.Join(HomeDir(), ".config/nvim")
 
 	files := []struct{ src, rel string }{
@@ -33,13 +42,149 @@ func (n *Neovim) Install(w io.Writer) error {
 		{"nvim/lua/plugins/init.lua", "lua/plugins/init.lua"},
 	}
 
+	var result []File
 	for _, f := range files {
-		dest := filepath.Join(nvimDir, f.rel)
-		if err := WriteEmbedded(w, &configs.FS, f.src, dest, 0o644); err != nil {
-			return err
-		}
+		result = append(result, File{Src: f.src, Dest: filepath.Join(nvimDir, f.rel), Perm: 0o644})
+	}
+	return result
+}
+
+func (n *Neovim) Install(w io.Writer) error {
+	if err := EnsurePackages(w, n.Packages()...); err != nil {
+		return err
+	}
+
+	if err := InstallFiles(w, n); err != nil {
+		return err
 	}
 
 	fmt.Fprintln(w, "  Neovim + LazyVim configured")
 	return nil
 }
+
+func (n *Neovim) Steps() []Step {
+	return []Step{{
+		Title:  "LazyVim",
+		Action: "Run nvim once to install LazyVim plugins",
+		Done:   appData(".local/share/nvim/lazy/lazy.nvim"),
+	}}
+}
+
+// Verify compiles every deployed Lua file under nvim --clean, which needs
+// no plugins. Once lazy.nvim is installed it also starts Neovim with the
+// config and fails when that left an error in v:errmsg. The startup check
+// is skipped before then, since it would clone every plugin, and a timeout
+// is only a warning: a slow network does not make the config broken.
+func (n *Neovim) Verify() error {
+	if _, err := exec.LookPath("nvim"); err != nil {
+		return nil
+	}
+	args := []string{"--clean", "--headless", "-c", luaCompileCheck, "-c", "qa!", "--"}
+	for _, f := range n.Files() {
+		if strings.HasSuffix(f.Dest, ".lua") {
+			args = append(args, f.Dest)
+		}
+	}
+	if err := verifyCommand(30*time.Second, "nvim", args...); err != nil {
+		return err
+	}
+
+	if !appData(".local/share/nvim/lazy/lazy.nvim")() {
+		return nil
+	}
+	err := verifyCommand(2*time.Minute, "nvim", "--headless", "-c", `if v:errmsg != "" | cq | endif`, "+qa")
+	if errors.Is(err, context.DeadlineExceeded) {
+		return verifyWarning{err}
+	}
+	return err
+}
+
+// luaCompileCheck loads every file in the argument list without running it
+// and exits non-zero on the first syntax error.
+const luaCompileCheck = `lua for _, f in ipairs(vim.fn.argv()) do local ok, err = loadfile(f); if not ok then io.stderr:write(err .. "\n"); vim.cmd("cq") end end`
+
+// PlanUpdate lists the plugins Lazy! sync would change, going by
+// lazy-lock.json: plugins that are missing, checked out at another commit
+// than the lock, or whose last fetched upstream is ahead of it.
+func (n *Neovim) PlanUpdate() ([]string, error) {
+	if _, err := exec.LookPath("nvim"); err != nil {
+		return nil, fmt.Errorf("nvim is not installed")
+	}
+	lazyDir := filepath.Join(HomeDir(), ".local/share/nvim/lazy")
+	if _, err := os.Stat(filepath.Join(lazyDir, "lazy.nvim")); err != nil {
+		return nil, fmt.Errorf("LazyVim plugins are not installed yet (open nvim once)")
+	}
+	lock, err := os.ReadFile(filepath.Join(HomeDir(), ".config/nvim/lazy-lock.json"))
+	if os.IsNotExist(err) {
+		return []string{"sync LazyVim plugins (no lazy-lock.json to compare with)"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lazyPlan(lock, lazyDir)
+}
+
+// lazyPlan compares the plugins in a lazy-lock.json with their checkouts
+// in lazyDir. It only uses what was fetched before and never goes online.
+func lazyPlan(lock []byte, lazyDir string) ([]string, error) {
+	var plugins map[string]struct {
+		Branch string `json:"branch"`
+		Commit string `json:"commit"`
+	}
+	if err := json.Unmarshal(lock, &plugins); err != nil {
+		return nil, fmt.Errorf("lazy-lock.json: %w", err)
+	}
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var plan []string
+	for _, name := range names {
+		p := plugins[name]
+		dir := filepath.Join(lazyDir, name)
+		if _, err := os.Stat(dir); err != nil {
+			plan = append(plan, "install "+name)
+			continue
+		}
+		head := gitRev(dir, "HEAD")
+		want := p.Commit
+		if p.Branch != "" {
+			if upstream := gitRev(dir, "origin/"+p.Branch); upstream != "" {
+				want = upstream
+			}
+		}
+		if head != "" && want != "" && head != want {
+			plan = append(plan, fmt.Sprintf("update %s (%s → %s)", name, shortRev(head), shortRev(want)))
+		}
+	}
+	return plan, nil
+}
+
+// gitRev resolves rev in the repository at dir, or returns "".
+func gitRev(dir, rev string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", rev).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func shortRev(rev string) string {
+	if len(rev) > 7 {
+		return rev[:7]
+	}
+	return rev
+}
+
+func (n *Neovim) Update(w io.Writer) error {
+	cmd := exec.Command("nvim", "--headless", "+Lazy! sync", "+qa")
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("syncing LazyVim plugins: %w", err)
+	}
+	fmt.Fprintln(w, "  LazyVim plugins synced")
+	return nil
+}
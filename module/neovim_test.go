@@ -0,0 +1,87 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lazyPlugin creates a plugin checkout in lazyDir with two commits and
+// returns both, checked out at the second.
+func lazyPlugin(t *testing.T, lazyDir, name string) (git func(args ...string) string, first, second string) {
+	t.Helper()
+	dir := filepath.Join(lazyDir, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git = func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "--quiet")
+	for _, msg := range []string{"one", "two"} {
+		git("commit", "--quiet", "--allow-empty", "-m", msg)
+		if first == "" {
+			first = git("rev-parse", "HEAD")
+		}
+	}
+	return git, first, git("rev-parse", "HEAD")
+}
+
+func TestLazyPlan(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	lazyDir := t.TempDir()
+
+	_, _, current := lazyPlugin(t, lazyDir, "current.nvim")
+	// Checked out behind the lock.
+	git, old, locked := lazyPlugin(t, lazyDir, "behind.nvim")
+	git("checkout", "--quiet", old)
+	// At the lock, with a newer upstream fetched by lazy's checker.
+	git, base, newer := lazyPlugin(t, lazyDir, "fetched.nvim")
+	git("update-ref", "refs/remotes/origin/main", newer)
+	git("checkout", "--quiet", base)
+
+	lock := fmt.Sprintf(`{
+  "current.nvim": { "branch": "main", "commit": %q },
+  "behind.nvim": { "branch": "main", "commit": %q },
+  "fetched.nvim": { "branch": "main", "commit": %q },
+  "missing.nvim": { "branch": "main", "commit": "abc" }
+}`, current, locked, base)
+
+	plan, err := lazyPlan([]byte(lock), lazyDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"update behind.nvim (" + old[:7] + " → " + locked[:7] + ")",
+		"update fetched.nvim (" + base[:7] + " → " + newer[:7] + ")",
+		"install missing.nvim",
+	}
+	if !reflect.DeepEqual(plan, want) {
+		t.Errorf("plan =\n%q\nwant\n%q", plan, want)
+	}
+
+	// Nothing to do when every plugin matches the lock.
+	plan, err = lazyPlan([]byte(fmt.Sprintf(`{"current.nvim": {"branch": "main", "commit": %q}}`, current)), lazyDir)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("plan = %q, %v; want an empty plan", plan, err)
+	}
+
+	if _, err := lazyPlan([]byte("{"), lazyDir); err == nil {
+		t.Error("lazyPlan accepted an invalid lock file")
+	}
+}
@@ -17,29 +17,117 @@ func (n *Node) Name() string         { return "Node.js + Package Managers" }
 func (n *Node) ID() string           { return "node" }
 func (n *Node) Description() string  { return "Install Node.js LTS via fnm, enable corepack" }
 func (n *Node) Dependencies() []string { return []string{"homebrew"} }
+func (n *Node) Tags() []string         { return []string{"system"} }
+func (n *Node) Platforms() []string    { return []string{"darwin"} }
+
+// nodeLTSCommands install the Node LTS release and make it the default.
+// They are skipped when it is already installed.
+var nodeLTSCommands = [][]string{
+	{"fnm", "install", "--lts"},
+	{"fnm", "default", "lts-latest"},
+}
+
+var corepackEnable = []string{"corepack", "enable"}
+
+// Commands lists what Install runs, leaving out the LTS install when it is
+// already done.
+func (n *Node) Commands() []string {
+	var cmds []string
+	if !n.ltsInstalled() {
+		for _, args := range nodeLTSCommands {
+			cmds = append(cmds, strings.Join(args, " "))
+		}
+	}
+	return append(cmds, strings.Join(corepackEnable, " "))
+}
+
+// Preflight checks that Node.js can be downloaded when the LTS release is
+// not installed yet. fnm itself comes from the homebrew dependency.
+func (n *Node) Preflight() []Result {
+	if n.ltsInstalled() {
+		return nil
+	}
+	return []Result{needHost("https://nodejs.org/dist/")}
+}
+
+func (n *Node) ltsInstalled() bool {
+	out, _ := exec.Command("fnm", "list").CombinedOutput()
+	return strings.Contains(string(out), "lts-latest")
+}
+
+// Check reports whether a node binary is on PATH.
+func (n *Node) Check() error {
+	_, err := exec.LookPath("node")
+	return err
+}
 
 func (n *Node) Install(w io.Writer) error {
 	// Check if Node LTS already installed via fnm
-	out, _ := exec.Command("fnm", "list").CombinedOutput()
-	if strings.Contains(string(out), "lts-latest") {
+	if n.ltsInstalled() {
 		fmt.Fprintln(w, "  Node LTS already installed")
 	} else {
 		fmt.Fprintln(w, "  Installing Node.js LTS via fnm...")
-		cmd := exec.Command("fnm", "install", "--lts")
+		install, setDefault := nodeLTSCommands[0], nodeLTSCommands[1]
+		cmd := exec.Command(install[0], install[1:]...)
 		cmd.Stdout = w
 		cmd.Stderr = w
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("installing Node.js: %w", err)
 		}
-		_ = exec.Command("fnm", "default", "lts-latest").Run()
+		_ = exec.Command(setDefault[0], setDefault[1:]...).Run()
 		fmt.Fprintln(w, "  Node.js LTS installed")
 	}
 
 	// Enable corepack
 	fmt.Fprintln(w, "  Enabling corepack...")
-	_ = exec.Command("corepack", "enable").Run()
+	_ = exec.Command(corepackEnable[0], corepackEnable[1:]...).Run()
 	fmt.Fprintln(w, "  corepack enabled (pnpm + yarn available)")
 
 	fmt.Fprintln(w, "  Bun + Deno already installed via Homebrew")
 	return nil
 }
+
+// latestLTS returns the newest Node LTS version known to fnm and whether it
+// is already installed.
+func (n *Node) latestLTS() (string, bool, error) {
+	out, err := exec.Command("fnm", "list-remote", "--lts").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("fnm list-remote: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) == 0 {
+		return "", false, fmt.Errorf("fnm list-remote returned no versions")
+	}
+	latest := fields[0]
+
+	installed, _ := exec.Command("fnm", "list").Output()
+	return latest, strings.Contains(string(installed), latest), nil
+}
+
+func (n *Node) PlanUpdate() ([]string, error) {
+	latest, installed, err := n.latestLTS()
+	if err != nil || installed {
+		return nil, err
+	}
+	return []string{"install Node.js LTS " + latest + " and make it the default"}, nil
+}
+
+func (n *Node) Update(w io.Writer) error {
+	latest, installed, err := n.latestLTS()
+	if err != nil || installed {
+		return err
+	}
+	cmd := exec.Command("fnm", "install", latest)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("installing Node.js %s: %w", latest, err)
+	}
+	if err := exec.Command("fnm", "default", latest).Run(); err != nil {
+		return fmt.Errorf("setting default Node.js: %w", err)
+	}
+	_ = exec.Command(corepackEnable[0], corepackEnable[1:]...).Run()
+	fmt.Fprintf(w, "  Node.js %s is the default\n", latest)
+	return nil
+}
@@ -0,0 +1,181 @@
+package module
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// PackageManager installs and queries system packages. Package names passed
+// to it are already mapped to the manager's own naming (see PackageName).
+type PackageManager interface {
+	Name() string
+	IsInstalled(pkg string) bool
+	Install(w io.Writer, pkgs ...string) error
+	Upgrade(w io.Writer, pkgs ...string) error
+	Remove(w io.Writer, pkgs ...string) error
+	List() ([]string, error)
+}
+
+// PackageIndex is implemented by package managers whose repositories may not
+// carry every package. Refresh updates the index, at most once per run, and
+// Available reports whether a package can be installed from it.
+type PackageIndex interface {
+	Refresh(w io.Writer) error
+	Available(pkg string) bool
+}
+
+// PackageRequirer is implemented by modules that need system packages.
+// Names are logical (Homebrew naming) and mapped per package manager.
+type PackageRequirer interface {
+	Packages() []string
+}
+
+// packageNames maps logical package names to their name in package managers
+// where it differs.
+var packageNames = map[string]map[string]string{
+	"fd":          {"apt": "fd-find"},
+	"imagemagick": {"dnf": "ImageMagick"},
+	"ffmpeg":      {"dnf": "ffmpeg-free"},
+	"node":        {"apt": "nodejs", "dnf": "nodejs"},
+	"python":      {"apt": "python3", "dnf": "python3"},
+}
+
+// PackageName returns the name of a logical package in the given package
+// manager.
+func PackageName(manager, pkg string) string {
+	if name, ok := packageNames[pkg][manager]; ok {
+		return name
+	}
+	return pkg
+}
+
+var packageManager PackageManager
+
+// SystemPackageManager returns the package manager for this machine, or nil
+// if none was found. HENRIK_OS_PACKAGE_MANAGER (brew, apt, dnf or fake)
+// overrides detection.
+func SystemPackageManager() PackageManager {
+	if packageManager == nil {
+		packageManager = detectPackageManager()
+	}
+	return packageManager
+}
+
+// SetPackageManager overrides the detected package manager.
+func SetPackageManager(pm PackageManager) {
+	packageManager = pm
+}
+
+func detectPackageManager() PackageManager {
+	switch os.Getenv("HENRIK_OS_PACKAGE_MANAGER") {
+	case "brew":
+		return &Brew{Path: brewPath()}
+	case "apt":
+		return &Apt{}
+	case "dnf":
+		return &Dnf{}
+	case "fake":
+		return NewFakePackageManager()
+	}
+
+	if platform == "linux" {
+		if _, err := exec.LookPath("apt-get"); err == nil {
+			return &Apt{}
+		}
+		if _, err := exec.LookPath("dnf"); err == nil {
+			return &Dnf{}
+		}
+	}
+	if path := brewPath(); path != "" {
+		return &Brew{Path: path}
+	}
+	return nil
+}
+
+// EnsurePackages installs any of the logical packages that are missing,
+// reporting each one on its own line.
+func EnsurePackages(w io.Writer, pkgs ...string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	pm := SystemPackageManager()
+	if pm == nil {
+		return fmt.Errorf("no supported package manager found")
+	}
+
+	// Package managers that can list everything at once are asked a single
+	// time instead of once per package.
+	installed := pm.IsInstalled
+	if inv, ok := pm.(interface {
+		Inventory() (map[string]string, error)
+	}); ok {
+		if inventory, err := inv.Inventory(); err == nil {
+			installed = func(name string) bool { return inInventory(inventory, name) }
+		}
+	}
+
+	var missing []string
+	for _, pkg := range pkgs {
+		name := PackageName(pm.Name(), pkg)
+		if installed(name) {
+			fmt.Fprintf(w, "    %-25s already installed\n", name)
+			continue
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// Skip what the repositories don't carry (starship, eza and zoxide are
+	// missing from dnf and older apt releases) rather than failing the
+	// whole batch.
+	if idx, ok := pm.(PackageIndex); ok {
+		if err := idx.Refresh(w); err != nil {
+			fmt.Fprintf(w, "  %v (using the existing index)\n", err)
+		}
+		var available []string
+		for _, name := range missing {
+			if idx.Available(name) {
+				available = append(available, name)
+			} else {
+				fmt.Fprintf(w, "    %-25s not available from %s (skipped)\n", name, pm.Name())
+			}
+		}
+		missing = available
+		if len(missing) == 0 {
+			return nil
+		}
+	}
+
+	fmt.Fprintf(w, "  Installing packages with %s...\n", pm.Name())
+	if err := pm.Install(w, missing...); err != nil {
+		return fmt.Errorf("installing packages: %w", err)
+	}
+	for _, name := range missing {
+		fmt.Fprintf(w, "    %-25s installed\n", name)
+	}
+	return nil
+}
+
+// packagesNeedSudo reports whether installing pkgs will need root, i.e. the
+// package manager is a system one and something is missing.
+func packagesNeedSudo(pkgs []string) bool {
+	pm := SystemPackageManager()
+	switch pm.(type) {
+	case *Apt, *Dnf:
+	default:
+		return false
+	}
+	if geteuid() == 0 {
+		return false
+	}
+	for _, pkg := range pkgs {
+		if !pm.IsInstalled(PackageName(pm.Name(), pkg)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package module
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeApt is the fake package manager under apt's name, so packages are
+// mapped to apt naming, with an index that lacks some packages.
+type fakeApt struct {
+	*FakePackageManager
+	unavailable map[string]bool
+	refreshes   int
+}
+
+func (f *fakeApt) Name() string { return "apt" }
+
+func (f *fakeApt) Refresh(w io.Writer) error {
+	f.refreshes++
+	return nil
+}
+
+func (f *fakeApt) Available(pkg string) bool { return !f.unavailable[pkg] }
+
+func usePackageManager(t *testing.T, pm PackageManager) {
+	t.Helper()
+	prev := packageManager
+	SetPackageManager(pm)
+	t.Cleanup(func() { SetPackageManager(prev) })
+}
+
+func TestPackageName(t *testing.T) {
+	tests := []struct{ manager, pkg, want string }{
+		{"apt", "fd", "fd-find"},
+		{"dnf", "fd", "fd"},
+		{"brew", "fd", "fd"},
+		{"dnf", "imagemagick", "ImageMagick"},
+		{"apt", "node", "nodejs"},
+		{"apt", "ripgrep", "ripgrep"},
+		{"fake", "fd", "fd"},
+	}
+	for _, tt := range tests {
+		if got := PackageName(tt.manager, tt.pkg); got != tt.want {
+			t.Errorf("PackageName(%q, %q) = %q, want %q", tt.manager, tt.pkg, got, tt.want)
+		}
+	}
+}
+
+func TestEnsurePackages(t *testing.T) {
+	fake := NewFakePackageManager("ripgrep")
+	usePackageManager(t, fake)
+
+	var out strings.Builder
+	if err := EnsurePackages(&out, "ripgrep", "fd", "fzf"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"install fd fzf"}; !reflect.DeepEqual(fake.Calls, want) {
+		t.Errorf("calls = %q, want %q", fake.Calls, want)
+	}
+	if !strings.Contains(out.String(), "ripgrep                   already installed") {
+		t.Errorf("output does not report ripgrep as installed:\n%s", out.String())
+	}
+
+	// Everything is installed now, so nothing else is called.
+	if err := EnsurePackages(io.Discard, "ripgrep", "fd", "fzf"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.Calls) != 1 {
+		t.Errorf("calls = %q after installing nothing", fake.Calls)
+	}
+}
+
+func TestEnsurePackagesMapsNames(t *testing.T) {
+	apt := &fakeApt{FakePackageManager: NewFakePackageManager("nodejs")}
+	usePackageManager(t, apt)
+
+	if err := EnsurePackages(io.Discard, "fd", "node", "ripgrep"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"install fd-find ripgrep"}; !reflect.DeepEqual(apt.Calls, want) {
+		t.Errorf("calls = %q, want %q", apt.Calls, want)
+	}
+}
+
+func TestEnsurePackagesSkipsUnavailable(t *testing.T) {
+	apt := &fakeApt{FakePackageManager: NewFakePackageManager(), unavailable: map[string]bool{"starship": true, "eza": true}}
+	usePackageManager(t, apt)
+
+	var out strings.Builder
+	if err := EnsurePackages(&out, "fish", "starship", "eza"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"install fish"}; !reflect.DeepEqual(apt.Calls, want) {
+		t.Errorf("calls = %q, want %q", apt.Calls, want)
+	}
+	if !strings.Contains(out.String(), "starship                  not available from apt (skipped)") {
+		t.Errorf("output does not report the skip:\n%s", out.String())
+	}
+	if apt.refreshes != 1 {
+		t.Errorf("index refreshed %d times, want once", apt.refreshes)
+	}
+
+	apt.Calls = nil
+	if err := EnsurePackages(io.Discard, "starship"); err != nil {
+		t.Fatal(err)
+	}
+	if len(apt.Calls) != 0 {
+		t.Errorf("calls = %q for an unavailable package", apt.Calls)
+	}
+}
+
+func TestFakeFromEnvironment(t *testing.T) {
+	usePackageManager(t, nil)
+	t.Setenv("HENRIK_OS_PACKAGE_MANAGER", "fake")
+	if err := EnsurePackages(io.Discard, "fd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := packageManager.(*FakePackageManager); !ok {
+		t.Errorf("HENRIK_OS_PACKAGE_MANAGER=fake selected %T", packageManager)
+	}
+	if err := EnsurePackages(io.Discard); err != nil {
+		t.Errorf("EnsurePackages without packages: %v", err)
+	}
+}
+
+// fakeBin is a directory of shell scripts put first on PATH in place of
+// real commands. Every script logs its name and arguments before running.
+type fakeBin struct {
+	dir string
+}
+
+func newFakeBin(t *testing.T) *fakeBin {
+	t.Helper()
+	b := &fakeBin{dir: t.TempDir()}
+	t.Setenv("PATH", b.dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return b
+}
+
+// add installs a fake command running the shell script body.
+func (b *fakeBin) add(t *testing.T, name, body string) {
+	t.Helper()
+	script := "#!/bin/sh\necho \"" + name + " $*\" >> \"" + filepath.Join(b.dir, "calls") + "\"\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(b.dir, name), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// calls returns the logged invocations, oldest first.
+func (b *fakeBin) calls() []string {
+	data, _ := os.ReadFile(filepath.Join(b.dir, "calls"))
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
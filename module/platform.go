@@ -0,0 +1,85 @@
+package module
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// platform is the operating system modules are installed on.
+var platform = runtime.GOOS
+
+// Platform returns the current operating system as reported by GOOS.
+func Platform() string {
+	return platform
+}
+
+// PlatformModule is implemented by modules that only work on some
+// platforms. Modules without it, or returning no platforms, are assumed to
+// work everywhere.
+type PlatformModule interface {
+	Platforms() []string
+}
+
+// Supported reports whether m can be installed on the current platform.
+func Supported(m Module) bool {
+	p, ok := m.(PlatformModule)
+	if !ok || len(p.Platforms()) == 0 {
+		return true
+	}
+	for _, goos := range p.Platforms() {
+		if goos == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// Available returns the registered modules supported on the current
+// platform, in registration order.
+func Available() []Module {
+	var result []Module
+	for _, m := range registry {
+		if Supported(m) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// SupportHint describes where an unsupported module can be used, e.g.
+// "macOS only". It returns "" for supported modules.
+func SupportHint(m Module) string {
+	if Supported(m) {
+		return ""
+	}
+	var names []string
+	for _, goos := range m.(PlatformModule).Platforms() {
+		names = append(names, PlatformName(goos))
+	}
+	return strings.Join(names, ", ") + " only"
+}
+
+// PlatformName returns a human-readable name for a GOOS value.
+func PlatformName(goos string) string {
+	switch goos {
+	case "darwin":
+		return "macOS"
+	case "linux":
+		return "Linux"
+	}
+	return goos
+}
+
+func unsupportedError(m Module) error {
+	return fmt.Errorf("module %s is not supported on %s (%s)", m.ID(), PlatformName(platform), SupportHint(m))
+}
+
+// brewDeps returns the homebrew dependency on macOS, where modules rely on
+// it for their binaries. Elsewhere the system package manager is used.
+func brewDeps() []string {
+	if platform == "darwin" {
+		return []string{"homebrew"}
+	}
+	return nil
+}
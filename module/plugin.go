@@ -0,0 +1,241 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginPrefix is the executable name prefix of plugin modules.
+const PluginPrefix = "henrik-os-module-"
+
+// Plugin is a module implemented by an external executable named
+// henrik-os-module-<id>. The executable is invoked with a single argument:
+//
+//	describe  print {"name", "description", "dependencies",
+//	          "soft_dependencies", "conflicts", "platforms", "tags",
+//	          "check"} as JSON
+//	install   install the module, streaming output
+//	check     exit 0 if the module is already installed; only called
+//	          when describe reports "check": true
+type Plugin struct {
+	id   string
+	path string
+	desc pluginDescription
+}
+
+type pluginDescription struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	Dependencies []string `json:"dependencies"`
+	Deps         []string `json:"deps"`
+	SoftDeps     []string `json:"soft_dependencies"`
+	Conflicts    []string `json:"conflicts"`
+	Platforms    []string `json:"platforms"`
+	Tags         []string `json:"tags"`
+	Check        bool     `json:"check"`
+}
+
+func (p *Plugin) Name() string               { return p.desc.Name }
+func (p *Plugin) ID() string                 { return p.id }
+func (p *Plugin) Description() string        { return p.desc.Description }
+func (p *Plugin) Dependencies() []string     { return p.desc.Dependencies }
+func (p *Plugin) Platforms() []string        { return p.desc.Platforms }
+func (p *Plugin) Conflicts() []string        { return p.desc.Conflicts }
+func (p *Plugin) SoftDependencies() []string { return p.desc.SoftDeps }
+func (p *Plugin) Tags() []string             { return p.desc.Tags }
+
+func (p *Plugin) Commands() []string { return []string{p.path + " install"} }
+
+// Path returns the plugin executable.
+func (p *Plugin) Path() string { return p.path }
+
+// pluginStdin is the input plugin installs read from. It is unset while the
+// TUI owns the terminal, so plugins cannot steal its key presses.
+var pluginStdin io.Reader
+
+// SetPluginStdin gives plugin installs an input, for headless runs where
+// nothing else reads the terminal.
+func SetPluginStdin(r io.Reader) {
+	pluginStdin = r
+}
+
+func (p *Plugin) Install(w io.Writer) error {
+	cmd := exec.Command(p.path, "install")
+	cmd.Stdin = pluginStdin
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s install: %w", filepath.Base(p.path), err)
+	}
+	return nil
+}
+
+// Verify runs the plugin's check after install, when it has one.
+func (p *Plugin) Verify() error {
+	if !p.desc.Check {
+		return nil
+	}
+	return p.Check()
+}
+
+// Check runs the plugin's check, or returns ErrNoCheck when describe did
+// not declare one.
+func (p *Plugin) Check() error {
+	if !p.desc.Check {
+		return ErrNoCheck
+	}
+	out, err := exec.Command(p.path, "check").CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("%s check: %w", filepath.Base(p.path), err)
+	}
+	return nil
+}
+
+// LoadPlugins registers every henrik-os-module-<id> executable found on
+// PATH. Like command lookup, the first match for an id wins. Plugins are
+// described in parallel, and descriptions are cached until the executable
+// changes. Plugins that fail to describe themselves are skipped and reported.
+func LoadPlugins() []error {
+	var found []*Plugin
+	seen := map[string]bool{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		paths, _ := filepath.Glob(filepath.Join(dir, PluginPrefix+"*"))
+		sort.Strings(paths)
+		for _, path := range paths {
+			id := strings.TrimPrefix(filepath.Base(path), PluginPrefix)
+			if id == "" || seen[id] || !isExecutable(path) {
+				continue
+			}
+			seen[id] = true
+			found = append(found, &Plugin{id: id, path: path})
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+
+	cache := readPluginCache()
+	errs := make([]error, len(found))
+	described := false
+	var wg sync.WaitGroup
+	for i, p := range found {
+		stamp := pluginStamp(p.path)
+		if c, ok := cache[p.path]; ok && c.Stamp == stamp {
+			p.desc = c.Desc
+			continue
+		}
+		described = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = describePlugin(p)
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	fresh := map[string]pluginCacheEntry{}
+	for i, p := range found {
+		err := errs[i]
+		if err == nil {
+			fresh[p.path] = pluginCacheEntry{Stamp: pluginStamp(p.path), Desc: p.desc}
+			if err = ValidateID(p.id); err == nil && ByID(p.id) != nil {
+				err = fmt.Errorf("module %s is already registered", p.id)
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", p.path, err))
+			continue
+		}
+		Register(p)
+	}
+	if described || len(fresh) != len(cache) {
+		writePluginCache(fresh)
+	}
+	return failed
+}
+
+func describePlugin(p *Plugin) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, p.path, "describe").Output()
+	if err != nil {
+		return fmt.Errorf("describe: %w", err)
+	}
+	if err := json.Unmarshal(out, &p.desc); err != nil {
+		return fmt.Errorf("describe: invalid JSON: %w", err)
+	}
+	if len(p.desc.Dependencies) == 0 {
+		p.desc.Dependencies = p.desc.Deps
+	}
+	if p.desc.Name == "" {
+		p.desc.Name = p.id
+	}
+	return nil
+}
+
+// pluginCacheEntry is a cached describe result. Stamp identifies the
+// executable it came from, so replacing the plugin invalidates it.
+type pluginCacheEntry struct {
+	Stamp string            `json:"stamp"`
+	Desc  pluginDescription `json:"desc"`
+}
+
+func pluginStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+}
+
+func pluginCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "henrik-os", "plugins.json")
+}
+
+func readPluginCache() map[string]pluginCacheEntry {
+	cache := map[string]pluginCacheEntry{}
+	if path := pluginCachePath(); path != "" {
+		data, _ := os.ReadFile(path)
+		_ = json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+func writePluginCache(cache map[string]pluginCacheEntry) {
+	path := pluginCachePath()
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	_ = os.WriteFile(path, data, 0o644)
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
@@ -0,0 +1,146 @@
+package module
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes a shell-script plugin into dir. Every describe call
+// appends a line to dir/<id>.described.
+func writePlugin(t *testing.T, dir, id, describe string, extra ...string) string {
+	t.Helper()
+	path := filepath.Join(dir, PluginPrefix+id)
+	script := `#!/bin/sh
+case "$1" in
+describe)
+	echo x >> "` + filepath.Join(dir, id+".described") + `"
+	echo '` + describe + `'
+	;;
+` + strings.Join(extra, "\n") + `
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func describeCount(t *testing.T, dir, id string) int {
+	t.Helper()
+	data, _ := os.ReadFile(filepath.Join(dir, id+".described"))
+	return strings.Count(string(data), "x")
+}
+
+// usePluginPath isolates the plugin cache and sets PATH to dirs.
+func usePluginPath(t *testing.T, dirs ...string) {
+	t.Helper()
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+	t.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+}
+
+func TestLoadPlugins(t *testing.T) {
+	useRegistry(t, &testModule{id: "fish"})
+	first, second := t.TempDir(), t.TempDir()
+	usePluginPath(t, first, second)
+
+	writePlugin(t, first, "tmux", `{"name": "tmux", "deps": ["fish"], "tags": ["terminal"]}`)
+	shadowed := writePlugin(t, second, "tmux", `{"name": "other tmux"}`)
+	writePlugin(t, first, "fish", `{"name": "my fish"}`)
+	writePlugin(t, first, "broken", `not json`)
+	writePlugin(t, first, "all", `{}`)
+	os.WriteFile(filepath.Join(first, PluginPrefix+"noexec"), []byte("#!/bin/sh\n"), 0o644)
+
+	errs := LoadPlugins()
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+	for _, want := range []string{"module fish is already registered", "invalid JSON", `id "all" is reserved`} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("LoadPlugins errors =\n%s\nwant one containing %q", joined, want)
+		}
+	}
+	if len(errs) != 3 {
+		t.Errorf("LoadPlugins returned %d errors, want 3", len(errs))
+	}
+
+	// The first tmux on PATH wins and the other is never described.
+	p, ok := ByID("tmux").(*Plugin)
+	if !ok || p.Name() != "tmux" || !HasTag(p, "terminal") {
+		t.Fatalf("tmux = %+v", ByID("tmux"))
+	}
+	if deps := p.Dependencies(); len(deps) != 1 || deps[0] != "fish" {
+		t.Errorf("deps = %v; the deps alias was not read", deps)
+	}
+	if n := describeCount(t, second, "tmux"); n != 0 {
+		t.Errorf("%s was described %d times", shadowed, n)
+	}
+	if _, ok := ByID("fish").(*testModule); !ok {
+		t.Error("a plugin replaced a built-in module")
+	}
+	if ByID("noexec") != nil {
+		t.Error("a non-executable file was registered")
+	}
+}
+
+func TestLoadPluginsCache(t *testing.T) {
+	dir := t.TempDir()
+	usePluginPath(t, dir)
+	load := func() *Plugin {
+		t.Helper()
+		useRegistry(t)
+		if errs := LoadPlugins(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		return ByID("tmux").(*Plugin)
+	}
+
+	writePlugin(t, dir, "tmux", `{"name": "tmux"}`)
+	load()
+	if p := load(); p.Name() != "tmux" || describeCount(t, dir, "tmux") != 1 {
+		t.Errorf("cached load: name %q, described %d times, want once", p.Name(), describeCount(t, dir, "tmux"))
+	}
+
+	// Replacing the executable invalidates its cached description.
+	writePlugin(t, dir, "tmux", `{"name": "tmux 2"}`)
+	if p := load(); p.Name() != "tmux 2" || describeCount(t, dir, "tmux") != 2 {
+		t.Errorf("after replacing: name %q, described %d times, want twice", p.Name(), describeCount(t, dir, "tmux"))
+	}
+}
+
+func TestPluginCheck(t *testing.T) {
+	dir := t.TempDir()
+	usePluginPath(t, dir)
+	useRegistry(t)
+	install := `install) echo installing ;;`
+	failingCheck := `check) echo "not yet" >&2; exit 1 ;;`
+	writePlugin(t, dir, "plain", `{}`, install, failingCheck)
+	writePlugin(t, dir, "checked", `{"check": true}`, install, failingCheck)
+	if errs := LoadPlugins(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	// Without "check" in describe the check is never run.
+	plain := ByID("plain").(*Plugin)
+	if err := plain.Install(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.Verify(); err != nil {
+		t.Errorf("Verify without a declared check = %v", err)
+	}
+	if err := plain.Check(); !errors.Is(err, ErrNoCheck) {
+		t.Errorf("Check without a declared check = %v, want ErrNoCheck", err)
+	}
+
+	checked := ByID("checked").(*Plugin)
+	if err := checked.Verify(); err == nil || err.Error() != "not yet" {
+		t.Errorf("Verify = %v, want the check's output", err)
+	}
+}
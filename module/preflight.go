@@ -0,0 +1,112 @@
+package module
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Preflighter is implemented by modules with requirements that can be
+// verified before anything is installed: binaries, OS version, disk space
+// or network access. Preflight runs after Resolve, so modules can rely on
+// Present for dependencies that are about to be installed.
+type Preflighter interface {
+	Preflight() []Result
+}
+
+// PreflightReport holds the problems found for one module.
+type PreflightReport struct {
+	Module  Module
+	Results []Result
+}
+
+// Failed reports whether any requirement failed.
+func (r PreflightReport) Failed() bool {
+	for _, res := range r.Results {
+		if res.Severity == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// Preflight checks the requirements of every module and returns a report
+// for each module that has warnings or failures.
+func Preflight(modules []Module) []PreflightReport {
+	var reports []PreflightReport
+	for _, m := range modules {
+		p, ok := m.(Preflighter)
+		if !ok {
+			continue
+		}
+		var problems []Result
+		for _, r := range p.Preflight() {
+			if r.Severity != Pass {
+				problems = append(problems, r)
+			}
+		}
+		if len(problems) > 0 {
+			reports = append(reports, PreflightReport{Module: m, Results: problems})
+		}
+	}
+	return reports
+}
+
+// needBinary fails unless name is on PATH.
+func needBinary(name, fix string) Result {
+	if _, err := exec.LookPath(name); err != nil {
+		return Result{Severity: Fail, Message: name + " not found", Fix: fix}
+	}
+	return passf("%s found", name)
+}
+
+// needHost warns unless url answers within a few seconds.
+func needHost(url string) Result {
+	if err := reachable(url); err != nil {
+		return Result{Severity: Warn, Message: err.Error(), Fix: "check your network connection, proxy or DNS"}
+	}
+	return passf("%s reachable", url)
+}
+
+// needDisk warns when less than gb gigabytes are free in the home
+// directory.
+func needDisk(gb float64) Result {
+	free, err := freeGB()
+	if err != nil || free >= gb {
+		return passf("disk space ok")
+	}
+	return Result{Severity: Warn, Message: fmt.Sprintf("only %.1f GB free, %.0f GB recommended", free, gb), Fix: "free up disk space"}
+}
+
+func reachable(url string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Head(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// freeGB returns the free space in the home directory. Tests replace it.
+var freeGB = func() (float64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(HomeDir(), &st); err != nil {
+		return 0, err
+	}
+	return float64(st.Bavail*uint64(st.Bsize)) / (1 << 30), nil
+}
+
+// macOSMajor returns the major macOS version, e.g. 14 for Sonoma.
+func macOSMajor() (int, error) {
+	out, err := exec.Command("sw_vers", "-productVersion").Output()
+	if err != nil {
+		return 0, fmt.Errorf("sw_vers: %w", err)
+	}
+	major, _, _ := strings.Cut(strings.TrimSpace(string(out)), ".")
+	return strconv.Atoi(major)
+}
@@ -0,0 +1,87 @@
+package module
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func useFreeGB(t *testing.T, gb float64, err error) {
+	t.Helper()
+	prev := freeGB
+	freeGB = func() (float64, error) { return gb, err }
+	t.Cleanup(func() { freeGB = prev })
+}
+
+func TestNeedDisk(t *testing.T) {
+	tests := []struct {
+		free float64
+		err  error
+		want Severity
+		msg  string
+	}{
+		{50, nil, Pass, "disk space ok"},
+		{10, nil, Pass, "disk space ok"},
+		{3.25, nil, Warn, "only 3.2 GB free, 10 GB recommended"},
+		// Unknown free space does not hold the install up.
+		{0, errors.New("statfs: no such file"), Pass, "disk space ok"},
+	}
+	for _, tt := range tests {
+		useFreeGB(t, tt.free, tt.err)
+		if r := needDisk(10); r.Severity != tt.want || r.Message != tt.msg {
+			t.Errorf("needDisk with %.2f GB free = %v %q, want %v %q", tt.free, r.Severity, r.Message, tt.want, tt.msg)
+		}
+	}
+}
+
+func TestNeedHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	if r := needHost(srv.URL); r.Severity != Pass {
+		t.Errorf("needHost(reachable) = %v %q", r.Severity, r.Message)
+	}
+	// Unreachable hosts only warn: the install may not need the network.
+	if r := needHost("http://127.0.0.1:1"); r.Severity != Warn || !strings.Contains(r.Message, "connection refused") || r.Fix == "" {
+		t.Errorf("needHost(unreachable) = %+v", r)
+	}
+}
+
+func TestNeedBinary(t *testing.T) {
+	newFakeBin(t).add(t, "present-tool", "")
+	if r := needBinary("present-tool", "install it"); r.Severity != Pass {
+		t.Errorf("needBinary(present) = %+v", r)
+	}
+	want := Result{Severity: Fail, Message: "absent-tool not found", Fix: "install it"}
+	if r := needBinary("absent-tool", "install it"); r != want {
+		t.Errorf("needBinary(absent) = %+v, want %+v", r, want)
+	}
+}
+
+// preflightModule reports fixed preflight results.
+type preflightModule struct {
+	testModule
+	results []Result
+}
+
+func (m *preflightModule) Preflight() []Result { return m.results }
+
+func TestPreflight(t *testing.T) {
+	ok := &preflightModule{testModule: testModule{id: "ok"}, results: []Result{passf("fine")}}
+	warns := &preflightModule{testModule: testModule{id: "warns"}, results: []Result{passf("fine"), {Severity: Warn, Message: "slow"}}}
+	fails := &preflightModule{testModule: testModule{id: "fails"}, results: []Result{{Severity: Fail, Message: "npm not found"}}}
+	plain := &testModule{id: "plain"}
+
+	reports := Preflight([]Module{ok, warns, plain, fails})
+	if len(reports) != 2 || reports[0].Module != warns || reports[1].Module != fails {
+		t.Fatalf("reports = %+v, want warns and fails", reports)
+	}
+	if want := []Result{{Severity: Warn, Message: "slow"}}; !reflect.DeepEqual(reports[0].Results, want) {
+		t.Errorf("passing results were reported: %+v", reports[0].Results)
+	}
+	if reports[0].Failed() || !reports[1].Failed() {
+		t.Error("only the report with a failure should fail")
+	}
+}
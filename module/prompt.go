@@ -0,0 +1,90 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Prompter is implemented by modules that need input from the user, such
+// as a passphrase. Prompt runs on the terminal before installation starts,
+// so nothing has to be asked while the TUI is showing.
+type Prompter interface {
+	Prompt() error
+}
+
+// PromptAll runs Prompt for every module that implements Prompter.
+func PromptAll(modules []Module) error {
+	for _, m := range modules {
+		if p, ok := m.(Prompter); ok {
+			if err := p.Prompt(); err != nil {
+				return fmt.Errorf("%s: %w", m.ID(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// terminal opens the controlling terminal, so commands can ask the user for
+// secrets themselves instead of receiving them as arguments.
+func terminal() (*os.File, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal to ask for the passphrase: %w", err)
+	}
+	return tty, nil
+}
+
+// runOnTerminal runs a command attached to tty.
+func runOnTerminal(tty *os.File, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = tty
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	return cmd.Run()
+}
+
+// keychainService is the macOS keychain service passphrases are stored
+// under, with the key file name as the account.
+const keychainService = "henrik-os-ssh"
+
+// keychainHasPassphrase reports whether a passphrase for account is stored
+// in the keychain. The secret itself is discarded.
+func keychainHasPassphrase(account string) bool {
+	return exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account).Run() == nil
+}
+
+// storeKeychainPassphrase saves a passphrase in the macOS keychain. security
+// asks for it on tty; a trailing -w without a value makes it prompt.
+func storeKeychainPassphrase(tty *os.File, account string) error {
+	fmt.Fprintf(tty, "Enter the passphrase for ~/.ssh/%s to store it in the keychain.\n", account)
+	return runOnTerminal(tty, "security", "add-generic-password", "-U", "-s", keychainService, "-a", account, "-w")
+}
+
+// keychainAskpass makes ssh-keygen and ssh-add read the passphrase of
+// account from the keychain through SSH_ASKPASS, so it never passes through
+// henrik-os or a command line. The returned function removes the helper.
+func keychainAskpass(cmd *exec.Cmd, account string) (func(), error) {
+	f, err := os.CreateTemp("", "henrik-os-askpass-*")
+	if err != nil {
+		return nil, err
+	}
+	script := "#!/bin/sh\nexec security find-generic-password -s " + keychainService + ` -a "$HENRIK_OS_KEYCHAIN_ACCOUNT" -w` + "\n"
+	_, err = f.WriteString(script)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o700)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	cmd.Env = append(os.Environ(),
+		"SSH_ASKPASS="+f.Name(),
+		"SSH_ASKPASS_REQUIRE=force",
+		"HENRIK_OS_KEYCHAIN_ACCOUNT="+account,
+	)
+	return func() { os.Remove(f.Name()) }, nil
+}
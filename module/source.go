@@ -0,0 +1,40 @@
+package module
+
+import (
+	"io/fs"
+
+	"github.com/henrikkvamme/henrik-os/configs"
+)
+
+// ConfigSource is a configs tree with the same layout as configs.FS.
+type ConfigSource interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// source is the configs tree files are deployed from. It defaults to the
+// configs compiled into the binary.
+var source ConfigSource = configs.FS
+
+// SetSource replaces the embedded configs with another tree, such as a
+// fetched git checkout or tarball.
+func SetSource(fsys fs.FS) {
+	source = sourceFS{fsys}
+}
+
+type sourceFS struct{ fsys fs.FS }
+
+func (s sourceFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(s.fsys, name)
+}
+
+// generatedSource serves configs rendered at runtime, such as files derived
+// from the manifest, keyed by their Src path.
+type generatedSource map[string]string
+
+func (g generatedSource) ReadFile(name string) ([]byte, error) {
+	data, ok := g[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return []byte(data), nil
+}
@@ -5,9 +5,11 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"os/user"
 	"path/filepath"
+	"strings"
 
-	"github.com/henrikkvamme/henrik-os/configs"
+	"github.com/henrikkvamme/henrik-os/github"
 )
 
 func init() {
@@ -18,8 +20,206 @@ type SSH struct{}
 
 func (s *SSH) Name() string         { return "SSH Key Generation" }
 func (s *SSH) ID() string           { return "ssh" }
-func (s *SSH) Description() string  { return "Generate SSH key and configure SSH" }
+func (s *SSH) Description() string  { return "Generate SSH keys and configure SSH" }
 func (s *SSH) Dependencies() []string { return nil }
+func (s *SSH) Tags() []string         { return []string{"system"} }
+
+// Files deploys the shared options from ssh/config followed by the Host
+// entries generated for each identity, as one managed block.
+func (s *SSH) Files() []File {
+	base, _ := source.ReadFile("ssh/config")
+	config := strings.TrimRight(string(base), "\n") + "\n\n" + sshIdentityConfig(manifest.sshIdentities())
+	return []File{
+		{Src: "ssh/config", Dest: filepath.Join(HomeDir(), ".ssh/config"), Perm: 0o600, Block: s.ID(),
+			FS: generatedSource{"ssh/config": config}, Previous: previousSSHConfigs},
+	}
+}
+
+// previousSSHConfigs are the ~/.ssh/config files earlier releases wrote
+// whole. Their Host * entry offers the primary key to every host before
+// any identity's key, so it is replaced by the block instead of kept.
+var previousSSHConfigs = []string{
+	`Host *
+  AddKeysToAgent yes
+  UseKeychain yes
+  IdentityFile ~/.ssh/id_ed25519
+
+Host github.com
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/id_ed25519
+`,
+}
+
+// sshIdentityConfig renders a Host entry per identity, followed by the
+// primary key as the default for every other host.
+func sshIdentityConfig(ids []Identity) string {
+	var b strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&b, "Host %s\n", id.Alias)
+		fmt.Fprintf(&b, "  HostName %s\n", id.Host)
+		b.WriteString("  User git\n")
+		fmt.Fprintf(&b, "  IdentityFile ~/.ssh/%s\n", id.Key)
+		b.WriteString("  IdentitiesOnly yes\n\n")
+	}
+	fmt.Fprintf(&b, "Host *\n  IdentityFile ~/.ssh/%s\n", ids[0].Key)
+	return b.String()
+}
+
+// keygenArgs returns the ssh-keygen arguments for id, without -N.
+func keygenArgs(id Identity) []string {
+	args := []string{"-t", id.Type}
+	if id.Type == "rsa" {
+		args = append(args, "-b", "4096")
+	}
+	return append(args, "-C", keyComment(id), "-f", id.KeyPath())
+}
+
+// keyComment is the identity's email, falling back to user@host. The git
+// email is not used: the git module may just have written the default one.
+func keyComment(id Identity) string {
+	if id.Email != "" {
+		return id.Email
+	}
+	host, _ := os.Hostname()
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return name + "@" + host
+}
+
+func (s *SSH) Commands() []string {
+	var cmds []string
+	for _, id := range manifest.sshIdentities() {
+		args := keygenArgs(id)
+		args[len(args)-1] = "~/.ssh/" + id.Key
+		cmds = append(cmds, fmt.Sprintf("ssh-keygen %s (passphrase: %s, if missing)", strings.Join(args, " "), id.Passphrase))
+		if Platform() == "darwin" && id.Passphrase != "prompt" {
+			cmds = append(cmds, "ssh-add --apple-use-keychain ~/.ssh/"+id.Key)
+		}
+	}
+	return cmds
+}
+
+// Prompt asks for what installation cannot do on its own: keys with
+// passphrase = "prompt" are generated here, with ssh-keygen asking on the
+// terminal, and keychain passphrases are stored unless the keychain
+// already has them. Nothing is asked for in a command line.
+func (s *SSH) Prompt() error {
+	var tty *os.File
+	open := func() error {
+		if tty != nil {
+			return nil
+		}
+		var err error
+		tty, err = terminal()
+		return err
+	}
+	defer func() {
+		if tty != nil {
+			tty.Close()
+		}
+	}()
+
+	for _, id := range manifest.sshIdentities() {
+		switch {
+		case id.Passphrase == "keychain" && !keychainHasPassphrase(id.Key):
+			if err := open(); err != nil {
+				return err
+			}
+			if err := storeKeychainPassphrase(tty, id.Key); err != nil {
+				return fmt.Errorf("storing the passphrase of ~/.ssh/%s in the keychain: %w", id.Key, err)
+			}
+		case id.Passphrase == "prompt" && !fileExists(id.KeyPath()):
+			if err := open(); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(id.KeyPath()), 0o700); err != nil {
+				return fmt.Errorf("creating .ssh directory: %w", err)
+			}
+			fmt.Fprintf(tty, "Generating %s SSH key ~/.ssh/%s...\n", id.Type, id.Key)
+			if err := runOnTerminal(tty, "ssh-keygen", keygenArgs(id)...); err != nil {
+				return fmt.Errorf("generating SSH key: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (s *SSH) Steps() []Step {
+	return []Step{
+		{Title: "GitHub CLI", Action: "gh auth login (SSH protocol recommended)", Done: succeeds("gh", "auth", "status")},
+		{Title: "SSH key on GitHub", Action: "henrik-os install ssh --github", Done: sshKeyOnGitHub},
+	}
+}
+
+// primaryKey returns the private key of the first identity, the one
+// uploaded to GitHub and used by default.
+func primaryKey() string {
+	return manifest.sshIdentities()[0].KeyPath()
+}
+
+// sshKeyOnGitHub reports whether the primary public key is registered on
+// the GitHub account of the available token.
+func sshKeyOnGitHub() bool {
+	pub, err := os.ReadFile(primaryKey() + ".pub")
+	if err != nil {
+		return false
+	}
+	c, err := github.New()
+	if err != nil {
+		return false
+	}
+	has, _ := c.HasKey(github.AuthKey, string(pub))
+	return has
+}
+
+// uploadAuthKey and uploadSigningKey enable uploading the public key to
+// GitHub after install. See SetGitHubUpload.
+var uploadAuthKey, uploadSigningKey bool
+
+// SetGitHubUpload makes the ssh module register its public key on GitHub
+// as an authentication key and, optionally, as a signing key.
+func SetGitHubUpload(auth, signing bool) {
+	uploadAuthKey, uploadSigningKey = auth, signing
+}
+
+// uploadKey registers the public key on GitHub and switches gh to SSH.
+// Problems are reported but do not fail the module; the manual step stays
+// pending instead.
+func uploadKey(w io.Writer, pubPath string) {
+	pub, err := os.ReadFile(pubPath)
+	if err != nil {
+		fmt.Fprintf(w, "  Skipping GitHub upload: %v\n", err)
+		return
+	}
+	c, err := github.New()
+	if err != nil {
+		fmt.Fprintf(w, "  Skipping GitHub upload: %v\n", err)
+		return
+	}
+
+	title, _ := os.Hostname()
+	kinds := []github.KeyKind{github.AuthKey}
+	if uploadSigningKey {
+		kinds = append(kinds, github.SigningKey)
+	}
+	for _, kind := range kinds {
+		if err := c.EnsureKey(w, kind, title, string(pub)); err != nil {
+			fmt.Fprintf(w, "  GitHub upload failed: %v\n", err)
+		}
+	}
+
+	if _, err := exec.LookPath("gh"); err == nil {
+		_ = exec.Command("gh", "config", "set", "git_protocol", "ssh").Run()
+	}
+}
 
 func (s *SSH) Install(w io.Writer) error {
 	sshDir := filepath.Join(HomeDir(), ".ssh")
@@ -27,29 +227,75 @@ func (s *SSH) Install(w io.Writer) error {
 		return fmt.Errorf("creating .ssh directory: %w", err)
 	}
 
-	keyPath := filepath.Join(sshDir, "id_ed25519")
-	if _, err := os.Stat(keyPath); err == nil {
-		fmt.Fprintln(w, "  SSH key already exists (skipping key generation)")
-	} else {
-		fmt.Fprintln(w, "  Generating SSH key...")
-		cmd := exec.Command("ssh-keygen", "-t", "ed25519",
-			"-C", "henrik.halvorsen.kvamme@gmail.com",
-			"-f", keyPath, "-N", "")
-		cmd.Stdout = w
-		cmd.Stderr = w
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("generating SSH key: %w", err)
+	for _, id := range manifest.sshIdentities() {
+		if err := s.generateKey(w, id); err != nil {
+			return err
 		}
 	}
 
-	// Write SSH config (always overwrite)
-	configPath := filepath.Join(sshDir, "config")
-	if err := WriteEmbedded(w, &configs.FS, "ssh/config", configPath, 0o600); err != nil {
+	// Maintain our blocks in the SSH config, keeping the user's own hosts
+	if err := InstallFiles(w, s); err != nil {
 		return err
 	}
 
-	// Add to keychain
-	_ = exec.Command("ssh-add", "--apple-use-keychain", keyPath).Run()
+	// Keys without a passphrase or with one in the keychain are added to
+	// the agent and the Apple keychain right away; prompted ones are added
+	// on first use (AddKeysToAgent, UseKeychain).
+	if Platform() == "darwin" {
+		for _, id := range manifest.sshIdentities() {
+			if id.Passphrase == "prompt" {
+				continue
+			}
+			add := exec.Command("ssh-add", "--apple-use-keychain", id.KeyPath())
+			if err := runWithPassphrase(id, add); err != nil {
+				fmt.Fprintf(w, "  Could not add ~/.ssh/%s to the keychain: %v\n", id.Key, err)
+			}
+		}
+	}
+
+	if uploadAuthKey || uploadSigningKey {
+		uploadKey(w, primaryKey()+".pub")
+	}
 	fmt.Fprintln(w, "  SSH configured")
 	return nil
 }
+
+// generateKey creates the key of an identity without a passphrase or with
+// one from the keychain. Keys with passphrase = "prompt" are generated by
+// Prompt. Existing private keys are never overwritten.
+func (s *SSH) generateKey(w io.Writer, id Identity) error {
+	keyPath := id.KeyPath()
+	if fileExists(keyPath) {
+		fmt.Fprintf(w, "  SSH key ~/.ssh/%s already exists (skipping key generation)\n", id.Key)
+		return nil
+	}
+	if id.Passphrase == "prompt" {
+		return fmt.Errorf("~/.ssh/%s needs a passphrase (run henrik-os install from a terminal)", id.Key)
+	}
+
+	fmt.Fprintf(w, "  Generating %s SSH key ~/.ssh/%s...\n", id.Type, id.Key)
+	args := keygenArgs(id)
+	if id.Passphrase == "none" {
+		args = append(args, "-N", "")
+	}
+	cmd := exec.Command("ssh-keygen", args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := runWithPassphrase(id, cmd); err != nil {
+		return fmt.Errorf("generating SSH key: %w", err)
+	}
+	return nil
+}
+
+// runWithPassphrase runs cmd, which for identities with passphrase =
+// "keychain" reads the passphrase from the keychain instead of a terminal.
+func runWithPassphrase(id Identity, cmd *exec.Cmd) error {
+	if id.Passphrase == "keychain" {
+		cleanup, err := keychainAskpass(cmd, id.Key)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+	return cmd.Run()
+}
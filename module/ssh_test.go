@@ -0,0 +1,73 @@
+package module
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSSHIdentityConfig(t *testing.T) {
+	m := &Manifest{
+		SSH: SSHOptions{Comment: "me@example.com"},
+		Identities: []Identity{
+			{Name: "personal", Alias: "github.com"},
+			{Name: "work", Email: "me@work.com", Type: "ecdsa"},
+		},
+	}
+	want := `Host github.com
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/id_ed25519_personal
+  IdentitiesOnly yes
+
+Host github.com-work
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/id_ecdsa_work
+  IdentitiesOnly yes
+
+Host *
+  IdentityFile ~/.ssh/id_ed25519_personal
+`
+	if got := sshIdentityConfig(m.sshIdentities()); got != want {
+		t.Errorf("config =\n%s\nwant\n%s", got, want)
+	}
+
+	// Without identities there is one default identity for github.com.
+	want = `Host github.com
+  HostName github.com
+  User git
+  IdentityFile ~/.ssh/id_rsa
+  IdentitiesOnly yes
+
+Host *
+  IdentityFile ~/.ssh/id_rsa
+`
+	if got := sshIdentityConfig((&Manifest{SSH: SSHOptions{Type: "rsa"}}).sshIdentities()); got != want {
+		t.Errorf("default config =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSSHConfigReplacesPreviousVersion(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prev := manifest
+	manifest = &Manifest{Identities: []Identity{{Name: "work"}}}
+	t.Cleanup(func() { manifest = prev })
+
+	path := filepath.Join(HomeDir(), ".ssh/config")
+	os.MkdirAll(filepath.Dir(path), 0o700)
+	own := "Host myserver\n  HostName 10.0.0.1\n"
+	for _, previous := range previousSSHConfigs {
+		os.WriteFile(path, []byte(previous+"\n"+own), 0o600)
+		if err := InstallFiles(io.Discard, &SSH{}); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		body, _ := (&SSH{}).Files()[0].FS.ReadFile("ssh/config")
+		want := string(RenderBlock(nil, "ssh", string(body))) + "\n" + own
+		if string(data) != want {
+			t.Errorf("config =\n%s\nwant\n%s", data, want)
+		}
+	}
+}
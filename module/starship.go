@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
-
-	"github.com/henrikkvamme/henrik-os/configs"
 )
 
 func init() {
@@ -17,11 +15,24 @@ type Starship struct{}
 func (s *Starship) Name() string         { return "Starship Prompt" }
 func (s *Starship) ID() string           { return "starship" }
 func (s *Starship) Description() string  { return "Configure Starship prompt" }
-func (s *Starship) Dependencies() []string { return []string{"homebrew"} }
+func (s *Starship) Dependencies() []string { return brewDeps() }
+func (s *Starship) Tags() []string         { return []string{"shell", "terminal"} }
+
+func (s *Starship) Packages() []string { return []string{"starship"} }
+
+func (s *Starship) Files() []File {
+	return []File{
+		{Src: "starship/starship.toml", Dest: filepath.Join(HomeDir(), ".config/starship.toml"), Perm: 0o644},
+	}
+}
+
+func (s *Starship) Verify() error { return verifyTOML(s.Files()[0].Dest) }
 
 func (s *Starship) Install(w io.Writer) error {
-	dest := filepath.Join(HomeDir(), ".config/starship.toml")
-	if err := WriteEmbedded(w, &configs.FS, "starship/starship.toml", dest, 0o644); err != nil {
+	if err := EnsurePackages(w, s.Packages()...); err != nil {
+		return err
+	}
+	if err := InstallFiles(w, s); err != nil {
 		return err
 	}
 	fmt.Fprintln(w, "  Starship configured")
@@ -0,0 +1,61 @@
+package module
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Step is a follow-up a module cannot do by itself, such as signing in to
+// an app. Done, when set, detects whether the step has been completed.
+type Step struct {
+	Title  string
+	Action string
+	Done   func() bool
+}
+
+// IsDone reports whether the step is known to be completed.
+func (s Step) IsDone() bool {
+	return s.Done != nil && s.Done()
+}
+
+// StepProvider is implemented by modules with manual follow-up steps.
+type StepProvider interface {
+	Steps() []Step
+}
+
+// Steps returns the manual steps of the given modules, in module order.
+func Steps(modules []Module) []Step {
+	var steps []Step
+	for _, m := range modules {
+		if p, ok := m.(StepProvider); ok {
+			steps = append(steps, p.Steps()...)
+		}
+	}
+	return steps
+}
+
+// succeeds reports whether a command exits 0; it backs most Done checks.
+func succeeds(name string, args ...string) func() bool {
+	return func() bool {
+		return exec.Command(name, args...).Run() == nil
+	}
+}
+
+// envSet detects steps completed by exporting an environment variable.
+func envSet(key string) func() bool {
+	return func() bool { return os.Getenv(key) != "" }
+}
+
+// appData detects apps that have been opened at least once, by the support
+// data they leave behind in the home directory.
+func appData(paths ...string) func() bool {
+	return func() bool {
+		for _, p := range paths {
+			if _, err := os.Stat(filepath.Join(HomeDir(), p)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+}
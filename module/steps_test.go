@@ -0,0 +1,55 @@
+package module
+
+import (
+	"reflect"
+	"testing"
+)
+
+// stepsModule is a test module with manual steps.
+type stepsModule struct {
+	testModule
+	steps []Step
+}
+
+func (m *stepsModule) Steps() []Step { return m.steps }
+
+func TestSteps(t *testing.T) {
+	a := &stepsModule{testModule: testModule{id: "a"}, steps: []Step{{Title: "a1"}, {Title: "a2"}}}
+	b := &stepsModule{testModule: testModule{id: "b"}, steps: []Step{{Title: "b1"}}}
+	plain := &testModule{id: "plain"}
+
+	var titles []string
+	for _, s := range Steps([]Module{b, plain, a}) {
+		titles = append(titles, s.Title)
+	}
+	if want := []string{"b1", "a1", "a2"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("Steps = %v, want %v in module order", titles, want)
+	}
+}
+
+func TestStepIsDone(t *testing.T) {
+	tests := []struct {
+		done func() bool
+		want bool
+	}{
+		{nil, false},
+		{func() bool { return false }, false},
+		{func() bool { return true }, true},
+	}
+	for i, tt := range tests {
+		if got := (Step{Done: tt.done}).IsDone(); got != tt.want {
+			t.Errorf("%d: IsDone = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestEnvSet(t *testing.T) {
+	t.Setenv("HENRIK_OS_TEST_TOKEN", "")
+	if envSet("HENRIK_OS_TEST_TOKEN")() {
+		t.Error("empty variable counted as set")
+	}
+	t.Setenv("HENRIK_OS_TEST_TOKEN", "x")
+	if !envSet("HENRIK_OS_TEST_TOKEN")() {
+		t.Error("set variable not detected")
+	}
+}
@@ -0,0 +1,115 @@
+package module
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SudoRequirer is implemented by modules that need root privileges for
+// part of their install. NeedsSudo should only report true when a
+// privileged step will actually run on this machine.
+type SudoRequirer interface {
+	NeedsSudo() bool
+}
+
+// SudoModules returns the modules that will need sudo, including those
+// whose packages must be installed with a system package manager.
+func SudoModules(modules []Module) []Module {
+	var result []Module
+	for _, m := range modules {
+		if s, ok := m.(SudoRequirer); ok && s.NeedsSudo() {
+			result = append(result, m)
+		} else if p, ok := m.(PackageRequirer); ok && packagesNeedSudo(p.Packages()) {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// geteuid and sudoKeepalive are variables so tests can run the sudo paths
+// as a regular user against a fake sudo.
+var (
+	geteuid       = os.Geteuid
+	sudoKeepalive = 60 * time.Second
+)
+
+// AcquireSudo asks for the sudo password once on the terminal and keeps the
+// credential cache warm until stop is called, so privileged steps later in
+// the run never prompt.
+func AcquireSudo() (stop func(), err error) {
+	if geteuid() == 0 {
+		return func() {}, nil
+	}
+
+	cmd := exec.Command("sudo", "-v")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return func() {}, fmt.Errorf("acquiring sudo: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(sudoKeepalive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = exec.Command("sudo", "-n", "-v").Run()
+			}
+		}
+	}()
+	return func() { close(done) }, nil
+}
+
+// All privileged operations go through sudoCommand, which logs each command
+// before it runs. sudo is invoked with -n so a missing credential fails
+// immediately instead of prompting behind the TUI. When already running as
+// root the command is run directly.
+func sudoCommand(w io.Writer, args ...string) *exec.Cmd {
+	fmt.Fprintf(w, "  sudo: %s\n", strings.Join(args, " "))
+	var cmd *exec.Cmd
+	if geteuid() == 0 {
+		cmd = exec.Command(args[0], args[1:]...)
+	} else {
+		cmd = exec.Command("sudo", append([]string{"-n"}, args...)...)
+	}
+	cmd.Stderr = w
+	return cmd
+}
+
+// SudoRun runs a command as root, streaming its output to w.
+func SudoRun(w io.Writer, name string, args ...string) error {
+	cmd := sudoCommand(w, append([]string{name}, args...)...)
+	cmd.Stdout = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sudo %s: %w", name, err)
+	}
+	return nil
+}
+
+// SudoWriteFile replaces the content of a root-owned file.
+func SudoWriteFile(w io.Writer, path string, data []byte) error {
+	cmd := sudoCommand(w, "tee", path)
+	cmd.Stdin = bytes.NewReader(data)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// SudoSymlink creates (or replaces) a symlink in a root-owned directory.
+func SudoSymlink(w io.Writer, target, link string) error {
+	if err := SudoRun(w, "ln", "-sf", target, link); err != nil {
+		return fmt.Errorf("linking %s: %w", link, err)
+	}
+	return nil
+}
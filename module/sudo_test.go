@@ -0,0 +1,169 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFakeSudo runs the sudo paths as a regular user against a fake sudo
+// that runs its command directly. sudo -v succeeds unless validate is
+// false, and with fail set every sudo -n call fails as if the credential
+// had expired.
+func useFakeSudo(t *testing.T, validate, fail bool) *fakeBin {
+	t.Helper()
+	prevEuid, prevKeepalive := geteuid, sudoKeepalive
+	geteuid = func() int { return 1000 }
+	t.Cleanup(func() { geteuid, sudoKeepalive = prevEuid, prevKeepalive })
+
+	body := `case "$1" in
+-v) exit ` + exitCode(!validate) + ` ;;
+-n) [ ` + exitCode(fail) + ` -eq 0 ] || { echo "sudo: a password is required" >&2; exit 1; }; shift ;;
+esac
+[ "$1" = -v ] && exit 0
+exec "$@"`
+	bin := newFakeBin(t)
+	bin.add(t, "sudo", body)
+	return bin
+}
+
+func exitCode(fail bool) string {
+	if fail {
+		return "1"
+	}
+	return "0"
+}
+
+func TestAcquireSudo(t *testing.T) {
+	bin := useFakeSudo(t, true, false)
+	sudoKeepalive = 10 * time.Millisecond
+
+	stop, err := AcquireSudo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The keepalive refreshes the ticket without prompting.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bin.calls()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	calls := bin.calls()
+	if len(calls) < 3 || calls[0] != "sudo -v" || calls[1] != "sudo -n -v" {
+		t.Fatalf("calls = %q, want sudo -v then keepalives", calls)
+	}
+
+	// Stopping ends the keepalive.
+	time.Sleep(20 * time.Millisecond)
+	n := len(bin.calls())
+	time.Sleep(50 * time.Millisecond)
+	if len(bin.calls()) != n {
+		t.Errorf("keepalive still running after stop: %q", bin.calls()[n:])
+	}
+}
+
+func TestAcquireSudoDeclined(t *testing.T) {
+	bin := useFakeSudo(t, false, false)
+	sudoKeepalive = 10 * time.Millisecond
+
+	stop, err := AcquireSudo()
+	if err == nil || !strings.Contains(err.Error(), "acquiring sudo") {
+		t.Fatalf("err = %v", err)
+	}
+	stop()
+	time.Sleep(50 * time.Millisecond)
+	if calls := bin.calls(); !reflect.DeepEqual(calls, []string{"sudo -v"}) {
+		t.Errorf("calls = %q, want no keepalive", calls)
+	}
+}
+
+func TestAcquireSudoAsRoot(t *testing.T) {
+	bin := useFakeSudo(t, true, false)
+	geteuid = func() int { return 0 }
+
+	stop, err := AcquireSudo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if calls := bin.calls(); calls != nil {
+		t.Errorf("calls = %q, want none as root", calls)
+	}
+}
+
+func TestSudoWriteFile(t *testing.T) {
+	bin := useFakeSudo(t, true, false)
+	path := filepath.Join(t.TempDir(), "shells")
+
+	var out strings.Builder
+	if err := SudoWriteFile(&out, path, []byte("/bin/sh\n/usr/bin/fish\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "/bin/sh\n/usr/bin/fish\n" {
+		t.Errorf("%s = %q", path, got)
+	}
+	if calls := bin.calls(); !reflect.DeepEqual(calls, []string{"sudo -n tee " + path}) {
+		t.Errorf("calls = %q", calls)
+	}
+	// The command is logged; tee's copy of the content is not.
+	if out.String() != "  sudo: tee "+path+"\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestSudoSymlink(t *testing.T) {
+	bin := useFakeSudo(t, true, false)
+	dir := t.TempDir()
+	link := filepath.Join(dir, "nvim")
+	os.Symlink("/old/nvim", link)
+
+	var out strings.Builder
+	if err := SudoSymlink(&out, "/opt/nvim/bin/nvim", link); err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := os.Readlink(link); target != "/opt/nvim/bin/nvim" {
+		t.Errorf("%s -> %q, want it replaced", link, target)
+	}
+	if calls := bin.calls(); !reflect.DeepEqual(calls, []string{"sudo -n ln -sf /opt/nvim/bin/nvim " + link}) {
+		t.Errorf("calls = %q", calls)
+	}
+}
+
+func TestSudoWithoutCredential(t *testing.T) {
+	useFakeSudo(t, true, true)
+	path := filepath.Join(t.TempDir(), "shells")
+
+	// sudo -n fails instead of prompting; the error says what was skipped
+	// and sudo's message reaches the log.
+	var out strings.Builder
+	err := SudoWriteFile(&out, path, []byte("x\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "writing "+path+": ") {
+		t.Errorf("SudoWriteFile err = %v", err)
+	}
+	if _, statErr := os.Stat(path); statErr == nil {
+		t.Error("file written without a credential")
+	}
+	if err := SudoSymlink(&out, "/target", filepath.Join(t.TempDir(), "link")); err == nil || !strings.Contains(err.Error(), "sudo ln") {
+		t.Errorf("SudoSymlink err = %v", err)
+	}
+	if !strings.Contains(out.String(), "a password is required") {
+		t.Errorf("output = %q, want sudo's error", out.String())
+	}
+}
+
+func TestSudoAsRoot(t *testing.T) {
+	bin := useFakeSudo(t, true, true)
+	geteuid = func() int { return 0 }
+	path := filepath.Join(t.TempDir(), "shells")
+
+	// As root the command runs without sudo.
+	if err := SudoWriteFile(&strings.Builder{}, path, []byte("x\n")); err != nil {
+		t.Fatal(err)
+	}
+	if calls := bin.calls(); calls != nil {
+		t.Errorf("calls = %q, want none as root", calls)
+	}
+}
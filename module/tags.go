@@ -0,0 +1,164 @@
+package module
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Tagger is implemented by modules that belong to one or more categories.
+// The first tag is the module's category in the TUI.
+type Tagger interface {
+	Tags() []string
+}
+
+// Categories lists the known tags in display order. Modules without tags,
+// or with an unknown first tag, are shown under "other".
+var Categories = []string{"shell", "editor", "terminal", "ai", "system", "other"}
+
+// Tags returns the tags of m, or nil when it has none.
+func Tags(m Module) []string {
+	if t, ok := m.(Tagger); ok {
+		return t.Tags()
+	}
+	return nil
+}
+
+// Category returns the category m is grouped under.
+func Category(m Module) string {
+	tags := Tags(m)
+	if len(tags) == 0 {
+		return "other"
+	}
+	for _, c := range Categories {
+		if c == tags[0] {
+			return c
+		}
+	}
+	return "other"
+}
+
+// CategoryName returns the display name of a category.
+func CategoryName(category string) string {
+	switch category {
+	case "ai":
+		return "AI"
+	}
+	return strings.ToUpper(category[:1]) + category[1:]
+}
+
+// HasTag reports whether m is tagged with tag.
+func HasTag(m Module, tag string) bool {
+	for _, t := range Tags(m) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateID checks that id can be selected by name: it must not be the
+// keyword all or contain selector syntax such as commas, globs or tag:.
+// IDs are letters, digits, '-', '_' and '.', starting with a letter or
+// digit.
+func ValidateID(id string) error {
+	if id == "all" {
+		return fmt.Errorf("id %q is reserved for selecting every module", id)
+	}
+	for i, r := range id {
+		alnum := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+		if !alnum && (i == 0 || !strings.ContainsRune("-_.", r)) {
+			return fmt.Errorf("invalid id %q: use letters, digits, '-', '_' and '.', starting with a letter or digit", id)
+		}
+	}
+	return nil
+}
+
+// Select expands module selectors into module IDs, in registration order.
+// Each argument may hold several comma-separated terms:
+//
+//	fish       a module ID
+//	claude*    a glob over module IDs
+//	tag:editor every module with the tag
+//	all        every module available on this platform
+//	-macos     remove matches of any of the above
+//
+// Terms apply left to right. When the first term is an exclusion the
+// selection starts from all. Exact IDs are passed through even when
+// unsupported so Resolve can report why; wider selectors only match
+// modules available on this platform.
+func Select(args []string) ([]string, error) {
+	var terms []string
+	for _, arg := range args {
+		for _, t := range strings.Split(arg, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				terms = append(terms, t)
+			}
+		}
+	}
+
+	chosen := make(map[string]bool)
+	for i, term := range terms {
+		exclude := strings.HasPrefix(term, "-")
+		if exclude {
+			term = term[1:]
+			if i == 0 {
+				for _, m := range Available() {
+					chosen[m.ID()] = true
+				}
+			}
+		}
+
+		matches, err := match(term)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matches {
+			chosen[id] = !exclude
+		}
+	}
+
+	var ids []string
+	for _, m := range registry {
+		if chosen[m.ID()] {
+			ids = append(ids, m.ID())
+		}
+	}
+	return ids, nil
+}
+
+func match(term string) ([]string, error) {
+	if m := ByID(term); m != nil {
+		return []string{m.ID()}, nil
+	}
+
+	var pred func(Module) bool
+	switch {
+	case term == "all":
+		pred = func(Module) bool { return true }
+	case strings.HasPrefix(term, "tag:"):
+		tag := strings.TrimPrefix(term, "tag:")
+		pred = func(m Module) bool { return HasTag(m, tag) }
+	case strings.ContainsAny(term, "*?["):
+		if _, err := path.Match(term, ""); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", term, err)
+		}
+		pred = func(m Module) bool {
+			ok, _ := path.Match(term, m.ID())
+			return ok
+		}
+	default:
+		return nil, fmt.Errorf("unknown module: %s", term)
+	}
+
+	var ids []string
+	for _, m := range Available() {
+		if pred(m) {
+			ids = append(ids, m.ID())
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("selector %q matches no modules", term)
+	}
+	return ids, nil
+}
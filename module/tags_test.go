@@ -0,0 +1,77 @@
+package module
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	useRegistry(t,
+		&testModule{id: "fish", tags: []string{"shell"}},
+		&testModule{id: "neovim", tags: []string{"editor"}},
+		&testModule{id: "vscode", tags: []string{"editor"}},
+		&testModule{id: "claude", tags: []string{"ai"}},
+		&testModule{id: "claude-config", tags: []string{"ai"}},
+		&testModule{id: "macos", tags: []string{"system"}, platforms: []string{"plan9"}},
+	)
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		err  string
+	}{
+		{name: "ids in registration order", args: []string{"vscode", "fish"}, want: []string{"fish", "vscode"}},
+		{name: "all skips unsupported", args: []string{"all"}, want: []string{"fish", "neovim", "vscode", "claude", "claude-config"}},
+		{name: "all minus one", args: []string{"all,-neovim"}, want: []string{"fish", "vscode", "claude", "claude-config"}},
+		{name: "separate arguments", args: []string{"all", "-neovim"}, want: []string{"fish", "vscode", "claude", "claude-config"}},
+		{name: "tag", args: []string{"tag:editor"}, want: []string{"neovim", "vscode"}},
+		{name: "tag and id", args: []string{"tag:editor, fish"}, want: []string{"fish", "neovim", "vscode"}},
+		{name: "glob", args: []string{"claude*"}, want: []string{"claude", "claude-config"}},
+		{name: "glob minus id", args: []string{"claude*,-claude"}, want: []string{"claude-config"}},
+		{name: "starts with exclusion", args: []string{"-tag:ai"}, want: []string{"fish", "neovim", "vscode"}},
+		{name: "later terms win", args: []string{"-fish,fish"}, want: []string{"fish", "neovim", "vscode", "claude", "claude-config"}},
+		{name: "unsupported id passes through", args: []string{"macos"}, want: []string{"macos"}},
+		{name: "empty terms", args: []string{"fish,,", " "}, want: []string{"fish"}},
+		{name: "unknown id", args: []string{"fish,nope"}, err: "unknown module: nope"},
+		{name: "unknown tag", args: []string{"tag:games"}, err: `selector "tag:games" matches no modules`},
+		{name: "tag of unsupported modules only", args: []string{"tag:system"}, err: "matches no modules"},
+		{name: "glob without match", args: []string{"zz*"}, err: "matches no modules"},
+		{name: "invalid glob", args: []string{"[fish"}, err: "invalid selector"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Select(%q) error = %v, want %q", tt.args, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%q) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want string
+	}{
+		{[]string{"editor"}, "editor"},
+		{[]string{"shell", "terminal"}, "shell"},
+		{[]string{"games"}, "other"},
+		{nil, "other"},
+	}
+	for _, tt := range tests {
+		if got := Category(&testModule{id: "m", tags: tt.tags}); got != tt.want {
+			t.Errorf("Category(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package module
+
+import (
+	"github.com/BurntSushi/toml"
+)
+
+// decodeTOML parses a TOML document into nested maps. Tables become
+// map[string]any, arrays []any, integers int64, floats float64 and dates
+// time.Time. Use tables to read an array of tables.
+func decodeTOML(data []byte) (map[string]any, error) {
+	doc := make(map[string]any)
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// tables returns the entries of an array of tables, which decodes as
+// []map[string]any when written with [[...]] headers and as []any when
+// written inline.
+func tables(v any) []any {
+	switch v := v.(type) {
+	case []map[string]any:
+		result := make([]any, len(v))
+		for i, t := range v {
+			result[i] = t
+		}
+		return result
+	case []any:
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package module
+
+import "io"
+
+// Updater is implemented by modules that can bring an existing install up
+// to date. PlanUpdate describes each change Update would make, one per
+// line, without changing anything; an empty plan means up to date.
+type Updater interface {
+	PlanUpdate() ([]string, error)
+	Update(w io.Writer) error
+}
@@ -0,0 +1,181 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Verifier is implemented by modules that can check the configs they just
+// wrote, e.g. by parsing them or asking the tool to load them.
+type Verifier interface {
+	Verify() error
+}
+
+// verifyWarning is a verification problem that does not show the configs
+// are broken, such as a check that timed out. It is reported but does not
+// roll the install back.
+type verifyWarning struct{ error }
+
+// InstallModule installs m and verifies the result. When verification
+// fails, the module's files are restored to their state before the
+// install and the verification error is returned.
+func InstallModule(w io.Writer, m Module) error {
+	v, ok := m.(Verifier)
+	if !ok {
+		return m.Install(w)
+	}
+
+	var snap snapshot
+	if fp, ok := m.(FileProvider); ok {
+		snap = takeSnapshot(fp.Files())
+	}
+	if err := m.Install(w); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "  Verifying...")
+	err := v.Verify()
+	var warning verifyWarning
+	if errors.As(err, &warning) {
+		fmt.Fprintf(w, "  Could not verify: %v\n", err)
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(w, "  Verification failed: %v\n", err)
+		if rerr := snap.restore(w); rerr != nil {
+			return fmt.Errorf("verification failed: %w (rollback failed: %v)", err, rerr)
+		}
+		return fmt.Errorf("verification failed, configs rolled back: %w", err)
+	}
+	fmt.Fprintln(w, "  Verified")
+	return nil
+}
+
+// snapshot records the destination files of a module before install.
+type snapshot []snapshotEntry
+
+type snapshotEntry struct {
+	path   string
+	exists bool
+	link   string // symlink target, if the file was a symlink
+	data   []byte
+	perm   os.FileMode
+}
+
+func takeSnapshot(files []File) snapshot {
+	var snap snapshot
+	for _, f := range files {
+		e := snapshotEntry{path: f.Dest}
+		if info, err := os.Lstat(f.Dest); err == nil {
+			e.exists = true
+			e.perm = info.Mode().Perm()
+			if info.Mode()&os.ModeSymlink != 0 {
+				e.link, _ = os.Readlink(f.Dest)
+			} else {
+				e.data, _ = os.ReadFile(f.Dest)
+			}
+		}
+		snap = append(snap, e)
+	}
+	return snap
+}
+
+func (s snapshot) restore(w io.Writer) error {
+	var errs []error
+	for _, e := range s {
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		var err error
+		switch {
+		case !e.exists:
+		case e.link != "":
+			err = os.Symlink(e.link, e.path)
+		default:
+			err = os.WriteFile(e.path, e.data, e.perm)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(w, "  Restored %s\n", e.path)
+	}
+	return errors.Join(errs...)
+}
+
+// verifyJSON checks that path holds valid JSON, or JSON with comments and
+// trailing commas when jsonc is set.
+func verifyJSON(path string, jsonc bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if jsonc {
+		data = stripJSONC(data)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyTOML checks that path holds valid TOML.
+func verifyTOML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := decodeTOML(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyPlist checks a property list with plutil when available, and
+// otherwise that it is well-formed XML.
+func verifyPlist(path string) error {
+	if _, err := exec.LookPath("plutil"); err == nil {
+		return verifyCommand(30*time.Second, "plutil", "-lint", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := xml.NewDecoder(f)
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+}
+
+// verifyCommand runs a validation command and includes its output in the
+// error when it fails.
+func verifyCommand(timeout time.Duration, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s: no result after %s: %w", name, timeout, ctx.Err())
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
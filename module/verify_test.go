@@ -0,0 +1,117 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifyModule deploys its files, makes them 0644 and fails verification
+// with err.
+type verifyModule struct {
+	testModule
+	files []File
+	err   error
+}
+
+func (m *verifyModule) Files() []File { return m.files }
+func (m *verifyModule) Verify() error { return m.err }
+func (m *verifyModule) Install(w io.Writer) error {
+	if err := InstallFiles(w, m); err != nil {
+		return err
+	}
+	for _, f := range m.files {
+		if err := os.Chmod(f.Dest, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newVerifyModule(t *testing.T, err error) (*verifyModule, string) {
+	t.Helper()
+	dir := t.TempDir()
+	gen := generatedSource{"a": "new a\n", "b": "new b\n", "c": "new c\n"}
+	m := &verifyModule{testModule: testModule{id: "verify"}, err: err}
+	for _, name := range []string{"a", "b", "c"} {
+		m.files = append(m.files, File{Src: name, Dest: filepath.Join(dir, name), Perm: 0o644, FS: gen})
+	}
+	// a and b exist with their own modes, c is new.
+	if err := os.WriteFile(filepath.Join(dir, "a"), []byte("old a\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b"), []byte("old b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return m, dir
+}
+
+func TestInstallModuleRollsBack(t *testing.T) {
+	m, dir := newVerifyModule(t, fmt.Errorf("config does not parse"))
+
+	var out strings.Builder
+	err := InstallModule(&out, m)
+	if err == nil || !strings.Contains(err.Error(), "configs rolled back: config does not parse") {
+		t.Fatalf("InstallModule = %v", err)
+	}
+
+	for name, perm := range map[string]os.FileMode{"a": 0o640, "b": 0o600} {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "old "+name+"\n" || info.Mode().Perm() != perm {
+			t.Errorf("%s = %q with mode %v; want the old content with mode %v", name, data, info.Mode().Perm(), perm)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "c")); !os.IsNotExist(err) {
+		t.Errorf("c was created by the install and not removed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Restored "+filepath.Join(dir, "a")) {
+		t.Errorf("output does not list the restored files:\n%s", out.String())
+	}
+}
+
+func TestInstallModuleKeepsVerifiedFiles(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		err    error
+		output string
+	}{
+		{"verified", nil, "Verified"},
+		{"timed out", verifyWarning{fmt.Errorf("nvim: no result after 2m0s: %w", context.DeadlineExceeded)}, "Could not verify: nvim: no result"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m, dir := newVerifyModule(t, tt.err)
+			var out strings.Builder
+			if err := InstallModule(&out, m); err != nil {
+				t.Fatalf("InstallModule = %v", err)
+			}
+			for _, name := range []string{"a", "b", "c"} {
+				if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != "new "+name+"\n" {
+					t.Errorf("%s = %q; the install was rolled back", name, data)
+				}
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output does not contain %q:\n%s", tt.output, out.String())
+			}
+		})
+	}
+}
+
+func TestVerifyCommandTimeout(t *testing.T) {
+	err := verifyCommand(50*time.Millisecond, "sleep", "5")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("verifyCommand = %v, want a deadline error", err)
+	}
+	if err := verifyCommand(time.Minute, "sh", "-c", "echo 'line 3: syntax error' >&2; exit 1"); err == nil || err.Error() != "sh: line 3: syntax error" {
+		t.Errorf("verifyCommand = %v, want the command's output", err)
+	}
+}
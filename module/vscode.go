@@ -1,14 +1,16 @@
 package module
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-
-	"github.com/henrikkvamme/henrik-os/configs"
+	"time"
 )
 
 func init() {
@@ -20,7 +22,8 @@ type VSCode struct{}
 func (v *VSCode) Name() string         { return "VS Code" }
 func (v *VSCode) ID() string           { return "vscode" }
 func (v *VSCode) Description() string  { return "Configure VS Code settings, extensions, and Neovim integration" }
-func (v *VSCode) Dependencies() []string { return []string{"homebrew"} }
+func (v *VSCode) Dependencies() []string { return brewDeps() }
+func (v *VSCode) Tags() []string         { return []string{"editor"} }
 
 var extensions = []string{
 	"daltonmenezes.aura-theme",
@@ -37,16 +40,76 @@ var extensions = []string{
 	"catppuccin.catppuccin-vsc-icons",
 }
 
+const codeCLI = "/Applications/Visual Studio Code.app/Contents/Resources/app/bin/code"
+
+// NeedsSudo reports whether the code CLI has to be symlinked into
+// /usr/local/bin.
+func (v *VSCode) NeedsSudo() bool {
+	if _, err := exec.LookPath("code"); err == nil {
+		return false
+	}
+	_, err := os.Stat(codeCLI)
+	return err == nil
+}
+
+func (v *VSCode) Files() []File {
+	settingsDir := filepath.Join(HomeDir(), "Library/Application Support/Code/User")
+	if Platform() == "linux" {
+		settingsDir = filepath.Join(HomeDir(), ".config/Code/User")
+	}
+	nvimVscodeDir := filepath.Join(HomeDir(), ".config/nvim-vscode")
+	return []File{
+		{Src: "vscode/settings.json", Dest: filepath.Join(settingsDir, "settings.json"), Perm: 0o644, Merge: v.merge},
+		{Src: "vscode/init.vim", Dest: filepath.Join(nvimVscodeDir, "init.vim"), Perm: 0o644},
+	}
+}
+
+// merge applies the embedded settings onto the user's settings.json,
+// keeping their own settings and comments. Keys listed under
+// [vscode] defaults in the manifest are only set when absent.
+func (v *VSCode) merge(existing, managed []byte) ([]byte, []string, error) {
+	return mergeJSONC(existing, managed, manifest.VSCode.Defaults)
+}
+
+func (v *VSCode) Commands() []string {
+	var cmds []string
+	if v.NeedsSudo() {
+		cmds = append(cmds, "sudo ln -sf "+codeCLI+" /usr/local/bin/code")
+	}
+	for _, ext := range extensions {
+		cmds = append(cmds, "code --install-extension "+ext+" --force")
+	}
+	return cmds
+}
+
+func (v *VSCode) Steps() []Step {
+	return []Step{{Title: "GitHub Copilot", Action: "Open VS Code → sign in to Copilot"}}
+}
+
+// Verify parses settings.json, which VS Code reads as JSON with comments.
+func (v *VSCode) Verify() error {
+	return verifyJSON(v.Files()[0].Dest, true)
+}
+
+// Preflight warns when VS Code itself is missing; settings are still
+// written but extensions cannot be installed.
+func (v *VSCode) Preflight() []Result {
+	if _, err := exec.LookPath("code"); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(codeCLI); err == nil {
+		return nil
+	}
+	return []Result{{Severity: Warn, Message: "VS Code not found, extensions will be skipped", Fix: "install VS Code first"}}
+}
+
 func (v *VSCode) Install(w io.Writer) error {
 	// Ensure code CLI is available
 	if _, err := exec.LookPath("code"); err != nil {
-		codeCLI := "/Applications/Visual Studio Code.app/Contents/Resources/app/bin/code"
 		if _, err := os.Stat(codeCLI); err == nil {
-			cmd := exec.Command("sudo", "ln", "-sf", codeCLI, "/usr/local/bin/code")
-			cmd.Stdout = w
-			cmd.Stderr = w
-			_ = cmd.Run()
-			fmt.Fprintln(w, "  Symlinked VS Code CLI")
+			if err := SudoSymlink(w, codeCLI, "/usr/local/bin/code"); err == nil {
+				fmt.Fprintln(w, "  Symlinked VS Code CLI")
+			}
 		} else {
 			fmt.Fprintln(w, "  VS Code not found - skipping CLI setup")
 		}
@@ -68,20 +131,158 @@ func (v *VSCode) Install(w io.Writer) error {
 		}
 	}
 
-	// Write settings.json
-	settingsDir := filepath.Join(HomeDir(), "Library/Application Support/Code/User")
-	if err := WriteEmbedded(w, &configs.FS, "vscode/settings.json",
-		filepath.Join(settingsDir, "settings.json"), 0o644); err != nil {
+	// Write settings.json and nvim-vscode init.vim
+	if err := InstallFiles(w, v); err != nil {
 		return err
 	}
 
-	// Write nvim-vscode init.vim
-	nvimVscodeDir := filepath.Join(HomeDir(), ".config/nvim-vscode")
-	if err := WriteEmbedded(w, &configs.FS, "vscode/init.vim",
-		filepath.Join(nvimVscodeDir, "init.vim"), 0o644); err != nil {
-		return err
+	fmt.Fprintln(w, "  VS Code configured")
+	return nil
+}
+
+// staleExtension is a managed extension with a newer marketplace release.
+type staleExtension struct {
+	id        string
+	installed string
+	latest    string
+}
+
+func (e staleExtension) String() string {
+	return fmt.Sprintf("%s %s → %s", e.id, e.installed, e.latest)
+}
+
+// staleExtensions compares the installed versions of managed extensions
+// with the latest release on the Visual Studio Marketplace.
+func (v *VSCode) staleExtensions() ([]staleExtension, error) {
+	out, err := exec.Command("code", "--list-extensions", "--show-versions").Output()
+	if err != nil {
+		return nil, fmt.Errorf("code --list-extensions: %w", err)
+	}
+	installed := make(map[string]string)
+	for _, line := range strings.Fields(string(out)) {
+		if id, version, ok := strings.Cut(line, "@"); ok {
+			installed[strings.ToLower(id)] = version
+		}
 	}
 
-	fmt.Fprintln(w, "  VS Code configured")
+	var managed []string
+	for _, ext := range extensions {
+		if _, ok := installed[strings.ToLower(ext)]; ok {
+			managed = append(managed, ext)
+		}
+	}
+	latest, err := marketplaceVersions(managed)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []staleExtension
+	for _, ext := range managed {
+		current := installed[strings.ToLower(ext)]
+		if l, ok := latest[strings.ToLower(ext)]; ok && l != current {
+			stale = append(stale, staleExtension{id: ext, installed: current, latest: l})
+		}
+	}
+	return stale, nil
+}
+
+// marketplaceURL is the Visual Studio Marketplace extension query endpoint.
+var marketplaceURL = "https://marketplace.visualstudio.com/_apis/public/gallery/extensionquery"
+
+// marketplaceVersions returns the latest stable version of each extension,
+// keyed by lower-cased extension ID.
+func marketplaceVersions(ids []string) (map[string]string, error) {
+	type criterion struct {
+		FilterType int    `json:"filterType"`
+		Value      string `json:"value"`
+	}
+	var criteria []criterion
+	for _, id := range ids {
+		criteria = append(criteria, criterion{FilterType: 7, Value: id})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"filters": []any{map[string]any{"criteria": criteria, "pageSize": len(ids)}},
+		"flags":   0x1 | 0x10 | 0x100, // versions, version properties, exclude non-validated
+	})
+
+	req, err := http.NewRequest("POST", marketplaceURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json;api-version=3.0-preview.1")
+	resp, err := (&http.Client{Timeout: 15 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("querying marketplace: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("querying marketplace: %s", resp.Status)
+	}
+
+	var result struct {
+		Results []struct {
+			Extensions []struct {
+				Name      string `json:"extensionName"`
+				Publisher struct {
+					Name string `json:"publisherName"`
+				} `json:"publisher"`
+				Versions []struct {
+					Version    string `json:"version"`
+					Properties []struct {
+						Key   string `json:"key"`
+						Value string `json:"value"`
+					} `json:"properties"`
+				} `json:"versions"`
+			} `json:"extensions"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("parsing marketplace response: %w", err)
+	}
+
+	latest := make(map[string]string)
+	for _, r := range result.Results {
+		for _, ext := range r.Extensions {
+			id := strings.ToLower(ext.Publisher.Name + "." + ext.Name)
+		versions:
+			for _, v := range ext.Versions {
+				for _, p := range v.Properties {
+					if p.Key == "Microsoft.VisualStudio.Code.PreRelease" && p.Value == "true" {
+						continue versions
+					}
+				}
+				latest[id] = v.Version
+				break
+			}
+		}
+	}
+	return latest, nil
+}
+
+func (v *VSCode) PlanUpdate() ([]string, error) {
+	stale, err := v.staleExtensions()
+	if err != nil {
+		return nil, err
+	}
+	var plan []string
+	for _, ext := range stale {
+		plan = append(plan, ext.String())
+	}
+	return plan, nil
+}
+
+func (v *VSCode) Update(w io.Writer) error {
+	stale, err := v.staleExtensions()
+	if err != nil {
+		return err
+	}
+	for _, ext := range stale {
+		if err := exec.Command("code", "--install-extension", ext.id, "--force").Run(); err != nil {
+			fmt.Fprintf(w, "    %-45s failed (non-fatal)\n", ext.id)
+		} else {
+			fmt.Fprintf(w, "    %-45s updated to %s\n", ext.id, ext.latest)
+		}
+	}
 	return nil
 }
@@ -17,6 +17,15 @@ func (x *Xcode) Name() string         { return "Xcode CLI Tools" }
 func (x *Xcode) ID() string           { return "xcode" }
 func (x *Xcode) Description() string  { return "Install Xcode Command Line Tools" }
 func (x *Xcode) Dependencies() []string { return nil }
+func (x *Xcode) Tags() []string         { return []string{"system"} }
+func (x *Xcode) Platforms() []string    { return []string{"darwin"} }
+
+func (x *Xcode) Commands() []string { return []string{"xcode-select --install"} }
+
+// Check reports whether the command line tools are installed.
+func (x *Xcode) Check() error {
+	return exec.Command("xcode-select", "-p").Run()
+}
 
 func (x *Xcode) Install(w io.Writer) error {
 	// Check if already installed
@@ -0,0 +1,42 @@
+package module
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML parses a YAML document into nested maps, like decodeTOML.
+// Mappings become map[string]any and sequences []any.
+func decodeYAML(data []byte) (map[string]any, error) {
+	doc := make(map[string]any)
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := checkYAMLKeys(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// checkYAMLKeys rejects mappings with non-string keys, which yaml.v3
+// decodes as map[any]any where the other decoders never would.
+func checkYAMLKeys(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, item := range v {
+			if err := checkYAMLKeys(item); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := checkYAMLKeys(item); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		return fmt.Errorf("mapping keys must be strings")
+	}
+	return nil
+}
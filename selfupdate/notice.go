@@ -0,0 +1,73 @@
+package selfupdate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkInterval is how often Notice asks the releases API.
+const checkInterval = 24 * time.Hour
+
+// Notice returns a one-line message when a newer release than current is
+// available, or "". The result of a check is cached for a day, failed
+// checks included, so most runs do not touch the network. Set
+// HENRIK_OS_NO_UPDATE_CHECK=1 to disable.
+func Notice(current string) string {
+	if os.Getenv("HENRIK_OS_NO_UPDATE_CHECK") != "" {
+		return ""
+	}
+	if _, ok := parseSemver(current); !ok {
+		return ""
+	}
+
+	latest, fresh := cachedLatest()
+	if !fresh {
+		u := New()
+		u.Client.Timeout = 2 * time.Second
+		r, err := u.Latest()
+		if err != nil {
+			// An empty cache records the failed check until the next one.
+			writeCache("")
+			return ""
+		}
+		latest = r.Version()
+		writeCache(latest)
+	}
+
+	if !Newer(latest, current) {
+		return ""
+	}
+	return fmt.Sprintf("henrik-os %s is available (you have %s) — run `henrik-os self-update`", latest, current)
+}
+
+func cachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "henrik-os", "latest-version")
+}
+
+// cachedLatest returns the cached latest version, which is "" after a
+// failed check, and whether the cache is recent enough to be used.
+func cachedLatest() (string, bool) {
+	path := cachePath()
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > checkInterval {
+		return "", false
+	}
+	data, _ := os.ReadFile(path)
+	return strings.TrimSpace(string(data)), true
+}
+
+func writeCache(latest string) {
+	path := cachePath()
+	if path == "" {
+		return
+	}
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	_ = os.WriteFile(path, []byte(latest+"\n"), 0o644)
+}
@@ -0,0 +1,268 @@
+// Package selfupdate checks GitHub releases for newer versions of henrik-os
+// and replaces the running binary with a verified release archive.
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the GitHub REST API. HENRIK_OS_RELEASES_URL overrides
+// it, e.g. to point at a local test server.
+const DefaultBaseURL = "https://api.github.com"
+
+// Repo is the GitHub repository releases are published to.
+const Repo = "henrikkvamme/henrik-os"
+
+// Release is a published GitHub release.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the release version without the leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// Updater talks to the releases API.
+type Updater struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// New returns an Updater using HENRIK_OS_RELEASES_URL or DefaultBaseURL.
+func New() *Updater {
+	base := os.Getenv("HENRIK_OS_RELEASES_URL")
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	return &Updater{BaseURL: base, Client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// Latest returns the latest published release.
+func (u *Updater) Latest() (*Release, error) {
+	url := strings.TrimRight(u.BaseURL, "/") + "/repos/" + Repo + "/releases/latest"
+	resp, err := u.get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var r Release
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("parsing release: %w", err)
+	}
+	if r.TagName == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	return &r, nil
+}
+
+func (u *Updater) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// Newer reports whether version latest is newer than current. Both are
+// semver strings with an optional "v" prefix; pre-release suffixes are
+// ignored. A current version that does not parse (e.g. "dev") is never
+// considered older.
+func Newer(latest, current string) bool {
+	l, ok := parseSemver(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseSemver(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+func parseSemver(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	v, _, _ = strings.Cut(v, "+")
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// HomebrewManaged reports whether exe lives inside a Homebrew prefix, in
+// which case `brew upgrade` should be used instead.
+func HomebrewManaged(exe string) bool {
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return strings.Contains(exe, "/Cellar/") || strings.Contains(exe, "/Caskroom/")
+}
+
+// Apply downloads the archive for this platform from r, verifies it against
+// the release checksums file and atomically replaces exe with the binary
+// inside it.
+func (u *Updater) Apply(r *Release, exe string) error {
+	suffix := fmt.Sprintf("_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	var archive, checksums *Asset
+	for i := range r.Assets {
+		a := &r.Assets[i]
+		switch {
+		case strings.HasSuffix(a.Name, suffix):
+			archive = a
+		case strings.HasSuffix(a.Name, "checksums.txt"):
+			checksums = a
+		}
+	}
+	if archive == nil {
+		return fmt.Errorf("release %s has no archive for %s/%s", r.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	if checksums == nil {
+		return fmt.Errorf("release %s has no checksums file", r.TagName)
+	}
+
+	want, err := u.checksum(checksums.URL, archive.Name)
+	if err != nil {
+		return err
+	}
+
+	resp, err := u.get(archive.URL)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", archive.Name, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", archive.Name, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", archive.Name, got, want)
+	}
+
+	binary, err := extractBinary(data, filepath.Base(exe))
+	if err != nil {
+		return err
+	}
+	return replace(exe, binary)
+}
+
+// checksum returns the expected sha256 of name from a goreleaser checksums
+// file ("<hex>  <name>" per line).
+func (u *Updater) checksum(url, name string) (string, error) {
+	resp, err := u.get(url)
+	if err != nil {
+		return "", fmt.Errorf("downloading checksums: %w", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("reading checksums: %w", err)
+	}
+	return "", fmt.Errorf("no checksum for %s", name)
+}
+
+// extractBinary returns the henrik-os binary from a tar.gz archive. The
+// running executable's name is accepted too, in case it was renamed.
+func extractBinary(archive []byte, exeName string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		name := filepath.Base(hdr.Name)
+		if hdr.Typeflag == tar.TypeReg && (name == "henrik-os" || name == exeName) {
+			return io.ReadAll(tr)
+		}
+	}
+	return nil, fmt.Errorf("archive does not contain a henrik-os binary")
+}
+
+// replace writes binary next to exe and renames it into place, so a failed
+// write never leaves a half-written executable behind.
+func replace(exe string, binary []byte) error {
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".henrik-os-update-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing update: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing update: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("writing update: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("replacing %s: %w", exe, err)
+	}
+	return nil
+}
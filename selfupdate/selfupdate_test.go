@@ -0,0 +1,280 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// tarball builds a tar.gz archive holding the given files.
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// releaseServer serves a latest release with an archive for this platform
+// and a checksums file listing sum for it.
+func releaseServer(t *testing.T, archive []byte, sum string) *httptest.Server {
+	t.Helper()
+	name := fmt.Sprintf("henrik-os_1.2.0_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/repos/"+Repo+"/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name": "v1.2.0", "assets": [
+			{"name": %q, "browser_download_url": %q},
+			{"name": "checksums.txt", "browser_download_url": %q}]}`,
+			name, srv.URL+"/archive", srv.URL+"/checksums")
+	})
+	mux.HandleFunc("/archive", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  henrik-os_1.2.0_other_arch.tar.gz\n%s  %s\n", strings.Repeat("0", 64), sum, name)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestLatest(t *testing.T) {
+	srv := releaseServer(t, nil, "")
+	r, err := (&Updater{BaseURL: srv.URL, Client: srv.Client()}).Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version() != "1.2.0" || len(r.Assets) != 2 {
+		t.Errorf("Latest = %+v", r)
+	}
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+	if _, err := (&Updater{BaseURL: failing.URL, Client: failing.Client()}).Latest(); err == nil {
+		t.Error("Latest succeeded against a server without releases")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	srv := releaseServer(t, nil, strings.Repeat("AB", 32))
+	u := &Updater{BaseURL: srv.URL, Client: srv.Client()}
+	name := fmt.Sprintf("henrik-os_1.2.0_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	got, err := u.checksum(srv.URL+"/checksums", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strings.Repeat("ab", 32) {
+		t.Errorf("checksum = %s", got)
+	}
+	if _, err := u.checksum(srv.URL+"/checksums", "missing.tar.gz"); err == nil {
+		t.Error("checksum found an entry for a missing archive")
+	}
+}
+
+func TestApply(t *testing.T) {
+	archive := tarball(t, map[string]string{"README.md": "docs", "henrik-os": "new binary"})
+
+	t.Run("replaces the executable", func(t *testing.T) {
+		srv := releaseServer(t, archive, sha(archive))
+		u := &Updater{BaseURL: srv.URL, Client: srv.Client()}
+		exe := filepath.Join(t.TempDir(), "henrik-os")
+		if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		r, err := u.Latest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Apply(r, exe); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(exe); string(data) != "new binary" {
+			t.Errorf("executable holds %q", data)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		srv := releaseServer(t, archive, strings.Repeat("0", 64))
+		u := &Updater{BaseURL: srv.URL, Client: srv.Client()}
+		exe := filepath.Join(t.TempDir(), "henrik-os")
+		if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		r, err := u.Latest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := u.Apply(r, exe); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("Apply = %v, want a checksum mismatch", err)
+		}
+		if data, _ := os.ReadFile(exe); string(data) != "old binary" {
+			t.Errorf("executable was replaced despite the mismatch: %q", data)
+		}
+	})
+}
+
+func TestExtractBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		exe     string
+		want    string
+		wantErr bool
+	}{
+		{name: "top level", files: map[string]string{"LICENSE": "x", "henrik-os": "bin"}, exe: "henrik-os", want: "bin"},
+		{name: "nested", files: map[string]string{"dist/henrik-os": "bin"}, exe: "henrik-os", want: "bin"},
+		{name: "renamed executable", files: map[string]string{"hos": "bin"}, exe: "hos", want: "bin"},
+		{name: "missing", files: map[string]string{"README.md": "x"}, exe: "henrik-os", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractBinary(tarball(t, tt.files), tt.exe)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("extractBinary succeeded with %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("extractBinary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := extractBinary([]byte("not gzip"), "henrik-os"); err == nil {
+		t.Error("extractBinary accepted a corrupt archive")
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"1.2.0", "1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"2.0.0", "1.99.99", true},
+		{"1.2.0", "1.2.0", false},
+		{"1.2.0", "1.3.0", false},
+		{"1.2.0-rc1", "1.1.0", true},
+		{"1.2.0", "dev", false},
+		{"latest", "1.0.0", false},
+		{"1.2", "1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestHomebrewManaged(t *testing.T) {
+	tests := []struct {
+		exe  string
+		want bool
+	}{
+		{"/opt/homebrew/Cellar/henrik-os/1.2.0/bin/henrik-os", true},
+		{"/usr/local/Caskroom/henrik-os/1.2.0/henrik-os", true},
+		{"/usr/local/bin/henrik-os", false},
+		{"/home/me/go/bin/henrik-os", false},
+	}
+	for _, tt := range tests {
+		if got := HomebrewManaged(tt.exe); got != tt.want {
+			t.Errorf("HomebrewManaged(%q) = %v, want %v", tt.exe, got, tt.want)
+		}
+	}
+
+	// A symlink into the Cellar, as Homebrew creates in its bin directory.
+	dir := t.TempDir()
+	target := filepath.Join(dir, "Cellar", "henrik-os", "1.2.0", "bin", "henrik-os")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "henrik-os")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if !HomebrewManaged(link) {
+		t.Error("HomebrewManaged did not follow the symlink into the Cellar")
+	}
+}
+
+func TestNoticeCachesFailures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HENRIK_OS_NO_UPDATE_CHECK", "")
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	t.Setenv("HENRIK_OS_RELEASES_URL", srv.URL)
+
+	for range 3 {
+		if n := Notice("1.0.0"); n != "" {
+			t.Errorf("Notice = %q after a failed check", n)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("releases API asked %d times, want once", hits)
+	}
+}
+
+func TestNotice(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HENRIK_OS_NO_UPDATE_CHECK", "")
+	srv := releaseServer(t, nil, "")
+	t.Setenv("HENRIK_OS_RELEASES_URL", srv.URL)
+
+	if n := Notice("1.0.0"); !strings.Contains(n, "1.2.0 is available") {
+		t.Errorf("Notice = %q", n)
+	}
+	srv.Close()
+	if n := Notice("1.0.0"); !strings.Contains(n, "1.2.0 is available") {
+		t.Errorf("cached Notice = %q", n)
+	}
+	if n := Notice("1.2.0"); n != "" {
+		t.Errorf("Notice for the latest version = %q", n)
+	}
+	if n := Notice("dev"); n != "" {
+		t.Errorf("Notice for a dev build = %q", n)
+	}
+}
@@ -0,0 +1,352 @@
+// Package source fetches configs trees from git repositories, local paths
+// or tarball URLs so they can replace the configs embedded in the binary.
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options control how a source is fetched and verified.
+type Options struct {
+	// Pin is the commit a git source must resolve to.
+	Pin string
+	// Checksum is the expected sha256 of a tarball, optionally prefixed
+	// with "sha256:".
+	Checksum string
+	// CacheDir holds fetched sources. Defaults to the user cache dir.
+	CacheDir string
+}
+
+// Fetch makes spec available locally and returns the directory holding its
+// configs tree. spec is a local path, a git URL, or an https URL to a
+// .tar.gz archive; an http URL is only accepted with a checksum. Progress
+// is written to w.
+func Fetch(w io.Writer, spec string, opts Options) (string, error) {
+	if opts.CacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("locating cache dir: %w", err)
+		}
+		opts.CacheDir = filepath.Join(dir, "henrik-os", "sources")
+	}
+
+	if opts.Checksum != "" && !isTarball(spec) {
+		return "", fmt.Errorf("a checksum can only be verified for tarball sources")
+	}
+	if opts.Pin != "" && isTarball(spec) {
+		return "", fmt.Errorf("a commit pin cannot be verified for tarball sources; use a checksum")
+	}
+	if isTarball(spec) && strings.HasPrefix(spec, "http://") && opts.Checksum == "" {
+		return "", fmt.Errorf("a tarball over plain http needs a checksum; use https or --source-checksum")
+	}
+	if opts.Pin != "" && !validPin(opts.Pin) {
+		return "", fmt.Errorf("pin %q must be a commit hash of at least 7 hex characters", opts.Pin)
+	}
+
+	var dir string
+	var err error
+	switch {
+	case isTarball(spec):
+		dir, err = fetchTarball(w, spec, opts)
+	case isGit(spec):
+		dir, err = fetchGit(w, spec, opts)
+	default:
+		dir, err = localPath(spec, opts)
+	}
+	if err != nil {
+		return "", err
+	}
+	return configsDir(dir)
+}
+
+// httpClient downloads tarballs. Tests replace it to trust their server.
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+func isTarball(spec string) bool {
+	return (strings.HasPrefix(spec, "https://") || strings.HasPrefix(spec, "http://")) &&
+		(strings.HasSuffix(spec, ".tar.gz") || strings.HasSuffix(spec, ".tgz"))
+}
+
+func isGit(spec string) bool {
+	for _, prefix := range []string{"git@", "git://", "ssh://", "https://", "http://"} {
+		if strings.HasPrefix(spec, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(spec, ".git")
+}
+
+// configsDir returns dir/configs when present, otherwise dir itself.
+func configsDir(dir string) (string, error) {
+	if info, err := os.Stat(filepath.Join(dir, "configs")); err == nil && info.IsDir() {
+		return filepath.Join(dir, "configs"), nil
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("source %s is not a directory", dir)
+	}
+	return dir, nil
+}
+
+func cacheKey(spec string) string {
+	sum := sha256.Sum256([]byte(spec))
+	return hex.EncodeToString(sum[:8])
+}
+
+func localPath(spec string, opts Options) (string, error) {
+	if strings.HasPrefix(spec, "~/") {
+		home, _ := os.UserHomeDir()
+		spec = filepath.Join(home, spec[2:])
+	}
+	dir, err := filepath.Abs(spec)
+	if err != nil {
+		return "", err
+	}
+	if opts.Pin != "" {
+		if err := verifyPin(dir, opts.Pin); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func fetchGit(w io.Writer, url string, opts Options) (string, error) {
+	dir := filepath.Join(opts.CacheDir, "git", cacheKey(url))
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		fmt.Fprintf(w, "  Cloning %s...\n", url)
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return "", err
+		}
+		if err := git(w, "", "clone", "--quiet", url, dir); err != nil {
+			return "", fmt.Errorf("cloning %s: %w", url, err)
+		}
+	} else {
+		fmt.Fprintf(w, "  Fetching %s...\n", url)
+		if err := git(w, dir, "fetch", "--quiet", "origin"); err != nil {
+			return "", fmt.Errorf("fetching %s: %w", url, err)
+		}
+	}
+
+	ref := "origin/HEAD"
+	if opts.Pin != "" {
+		ref = opts.Pin
+	}
+	if err := git(w, dir, "checkout", "--quiet", "--detach", ref); err != nil {
+		return "", fmt.Errorf("checking out %s: %w", ref, err)
+	}
+	if opts.Pin != "" {
+		if err := verifyPin(dir, opts.Pin); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func git(w io.Writer, dir string, args ...string) error {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return cmd.Run()
+}
+
+// validPin reports whether pin looks like a full or abbreviated commit hash
+// long enough to be meaningful.
+func validPin(pin string) bool {
+	if len(pin) < 7 || len(pin) > 40 {
+		return false
+	}
+	_, err := hex.DecodeString(pin + strings.Repeat("0", len(pin)%2))
+	return err == nil
+}
+
+// verifyPin checks that the git checkout in dir is at commit pin. The pin
+// is resolved by git, so an abbreviated hash must name exactly one commit.
+func verifyPin(dir, pin string) error {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("cannot verify pin: %s is not a git checkout", dir)
+	}
+	head := strings.TrimSpace(string(out))
+	out, err = exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", strings.ToLower(pin)+"^{commit}").Output()
+	if err != nil {
+		return fmt.Errorf("pinned commit %s not found in the source", pin)
+	}
+	if commit := strings.TrimSpace(string(out)); commit != head {
+		return fmt.Errorf("source is at %s, expected pinned commit %s", head, commit)
+	}
+	return nil
+}
+
+// Files kept next to an extracted tarball: the sha256 of the archive and
+// the ETag and Last-Modified headers it was served with.
+const (
+	checksumStamp  = ".henrik-os-sha256"
+	validatorStamp = ".henrik-os-validators"
+)
+
+// fetchTarball downloads and extracts an archive into a cache keyed by URL.
+// With a checksum the cached extraction is reused as long as it was verified
+// against the same checksum. Without one the download is revalidated with
+// the server, and the cached extraction is reused when it is unchanged.
+func fetchTarball(w io.Writer, url string, opts Options) (string, error) {
+	want := strings.ToLower(strings.TrimPrefix(opts.Checksum, "sha256:"))
+	dir := filepath.Join(opts.CacheDir, "tarball", cacheKey(url))
+	got, err := os.ReadFile(filepath.Join(dir, checksumStamp))
+	cached := err == nil
+
+	if want != "" && cached && strings.TrimSpace(string(got)) == want {
+		return extractedRoot(dir)
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	action := "Downloading"
+	if want == "" && cached {
+		action = "Checking"
+		validators, _ := os.ReadFile(filepath.Join(dir, validatorStamp))
+		etag, modified, _ := strings.Cut(string(validators), "\n")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified = strings.TrimSpace(modified); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	fmt.Fprintf(w, "  %s %s...\n", action, url)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cached {
+		fmt.Fprintln(w, "  Unchanged, using the cached copy")
+		return extractedRoot(dir)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", url, err)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if want != "" && checksum != want {
+		return "", fmt.Errorf("checksum mismatch for %s: got %s, want %s", url, checksum, want)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), ".extract-")
+	if err != nil {
+		return "", err
+	}
+	if err := extract(data, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	validators := resp.Header.Get("ETag") + "\n" + resp.Header.Get("Last-Modified") + "\n"
+	if err := os.WriteFile(filepath.Join(tmp, checksumStamp), []byte(checksum+"\n"), 0o644); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, validatorStamp), []byte(validators), 0o644); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	os.RemoveAll(dir)
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	return extractedRoot(dir)
+}
+
+// extractedRoot descends into the single top-level directory that archives
+// such as GitHub's source tarballs wrap their content in.
+func extractedRoot(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var dirs []os.DirEntry
+	for _, e := range entries {
+		if e.Name() == checksumStamp || e.Name() == validatorStamp {
+			continue
+		}
+		if !e.IsDir() {
+			return dir, nil
+		}
+		dirs = append(dirs, e)
+	}
+	if len(dirs) == 1 {
+		return filepath.Join(dir, dirs[0].Name()), nil
+	}
+	return dir, nil
+}
+
+func extract(data []byte, dest string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+
+		// Archives made with tar -C dir . start with the destination itself.
+		target := filepath.Join(dest, hdr.Name)
+		root := filepath.Clean(dest)
+		if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return fmt.Errorf("archive entry %s escapes the destination", hdr.Name)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("archive entry %s is a link to %s; links are not supported", hdr.Name, hdr.Linkname)
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode)&0o777)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tarball builds a tar.gz archive holding the given files, in order. Names
+// ending in / are directories.
+func tarball(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var hdrs []*tar.Header
+	for _, name := range files {
+		if strings.HasSuffix(name, "/") {
+			hdrs = append(hdrs, &tar.Header{Name: name, Mode: 0o755, Typeflag: tar.TypeDir})
+		} else {
+			hdrs = append(hdrs, &tar.Header{Name: name, Mode: 0o644, Typeflag: tar.TypeReg})
+		}
+	}
+	return archive(t, hdrs...)
+}
+
+// archive builds a tar.gz archive from headers. Regular files get their
+// name as content.
+func archive(t *testing.T, hdrs ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range hdrs {
+		body := ""
+		if hdr.Typeflag == tar.TypeReg {
+			body = "content of " + hdr.Name
+			hdr.Size = int64(len(body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tarballServer serves archive over https at /configs.tar.gz with an ETag
+// and counts the full downloads.
+func tarballServer(t *testing.T, archive []byte) (url string, downloads *int) {
+	t.Helper()
+	downloads = new(int)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		*downloads++
+		w.Write(archive)
+	}))
+	t.Cleanup(srv.Close)
+	prev := httpClient
+	httpClient = srv.Client()
+	t.Cleanup(func() { httpClient = prev })
+	return srv.URL + "/configs.tar.gz", downloads
+}
+
+func sha(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExtractRejectsEscapes(t *testing.T) {
+	for _, name := range []string{"../evil", "configs/../../evil", "/../evil"} {
+		dest := filepath.Join(t.TempDir(), "dest")
+		err := extract(tarball(t, "configs/ok", name), dest)
+		if err == nil || !strings.Contains(err.Error(), "escapes the destination") {
+			t.Errorf("extract with entry %q = %v, want an escape error", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil")); err == nil {
+			t.Errorf("entry %q was written outside the destination", name)
+		}
+	}
+}
+
+func TestExtractDotRoot(t *testing.T) {
+	// tar -C dir -czf x.tgz . names every entry relative to ./
+	dest := filepath.Join(t.TempDir(), "dest")
+	if err := extract(tarball(t, "./", "./configs/", "./configs/fish/config.fish"), dest); err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, "configs/fish/config.fish"))
+	if err != nil || string(data) != "content of ./configs/fish/config.fish" {
+		t.Errorf("config.fish = %q, %v", data, err)
+	}
+
+	for _, name := range []string{"../", "./../evil"} {
+		err := extract(tarball(t, "./", name), filepath.Join(t.TempDir(), "dest"))
+		if err == nil || !strings.Contains(err.Error(), "escapes the destination") {
+			t.Errorf("extract with entry %q = %v, want an escape error", name, err)
+		}
+	}
+}
+
+func TestExtractRejectsLinks(t *testing.T) {
+	for _, typ := range []byte{tar.TypeSymlink, tar.TypeLink} {
+		data := archive(t,
+			&tar.Header{Name: "configs/", Mode: 0o755, Typeflag: tar.TypeDir},
+			&tar.Header{Name: "configs/passwd", Linkname: "/etc/passwd", Typeflag: typ},
+		)
+		err := extract(data, filepath.Join(t.TempDir(), "dest"))
+		if err == nil || !strings.Contains(err.Error(), "configs/passwd is a link") {
+			t.Errorf("extract with link type %q = %v, want a link error", typ, err)
+		}
+	}
+}
+
+func TestFetchTarball(t *testing.T) {
+	archive := tarball(t, "repo-main/configs/fish/config.fish")
+
+	t.Run("extracts the configs tree", func(t *testing.T) {
+		url, _ := tarballServer(t, archive)
+		dir, err := Fetch(io.Discard, url, Options{CacheDir: t.TempDir(), Checksum: "sha256:" + sha(archive)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "fish", "config.fish")); string(data) != "content of repo-main/configs/fish/config.fish" {
+			t.Errorf("Fetch returned %s holding %q", dir, data)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		url, _ := tarballServer(t, archive)
+		cache := t.TempDir()
+		_, err := Fetch(io.Discard, url, Options{CacheDir: cache, Checksum: strings.Repeat("0", 64)})
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("Fetch = %v, want a checksum mismatch", err)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(cache, "tarball")); len(entries) != 0 {
+			t.Errorf("mismatching archive was cached: %v", entries)
+		}
+	})
+
+	t.Run("reuses the cache on 304", func(t *testing.T) {
+		url, downloads := tarballServer(t, archive)
+		cache := t.TempDir()
+		first, err := Fetch(io.Discard, url, Options{CacheDir: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		second, err := Fetch(&out, url, Options{CacheDir: cache})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != second {
+			t.Errorf("second Fetch returned %s, want %s", second, first)
+		}
+		if *downloads != 1 {
+			t.Errorf("archive downloaded %d times, want once", *downloads)
+		}
+		if !strings.Contains(out.String(), "Unchanged") {
+			t.Errorf("output %q", out.String())
+		}
+	})
+
+	t.Run("reuses a verified cache without asking", func(t *testing.T) {
+		url, downloads := tarballServer(t, archive)
+		opts := Options{CacheDir: t.TempDir(), Checksum: sha(archive)}
+		for range 2 {
+			if _, err := Fetch(io.Discard, url, opts); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if *downloads != 1 {
+			t.Errorf("archive downloaded %d times, want once", *downloads)
+		}
+	})
+
+	t.Run("plain http needs a checksum", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		}))
+		defer srv.Close()
+		url := srv.URL + "/configs.tar.gz"
+		if _, err := Fetch(io.Discard, url, Options{CacheDir: t.TempDir()}); err == nil || !strings.Contains(err.Error(), "needs a checksum") {
+			t.Errorf("Fetch without checksum = %v, want it refused", err)
+		}
+		if _, err := Fetch(io.Discard, url, Options{CacheDir: t.TempDir(), Checksum: sha(archive)}); err != nil {
+			t.Errorf("Fetch with checksum: %v", err)
+		}
+	})
+
+	t.Run("rejects a pin", func(t *testing.T) {
+		url, _ := tarballServer(t, archive)
+		if _, err := Fetch(io.Discard, url, Options{CacheDir: t.TempDir(), Pin: "abcdef0"}); err == nil {
+			t.Error("Fetch accepted a commit pin for a tarball")
+		}
+	})
+}
+
+func TestValidPin(t *testing.T) {
+	tests := []struct {
+		pin  string
+		want bool
+	}{
+		{"abcdef0", true},
+		{"ABCDEF01", true},
+		{strings.Repeat("a", 40), true},
+		{"abc123", false},
+		{strings.Repeat("a", 41), false},
+		{"main", false},
+		{"v1.2.0xx", false},
+		{"abcdefg", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validPin(tt.pin); got != tt.want {
+			t.Errorf("validPin(%q) = %v, want %v", tt.pin, got, tt.want)
+		}
+	}
+}
+
+// gitRepo creates a repository with two commits and returns its path and
+// the hashes of the first and second commit.
+func gitRepo(t *testing.T) (dir, first, second string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir = t.TempDir()
+	run := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "--quiet")
+	for i, name := range []string{"a", "b"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", name)
+		run("commit", "--quiet", "-m", name)
+		if i == 0 {
+			first = run("rev-parse", "HEAD")
+		}
+	}
+	return dir, first, run("rev-parse", "HEAD")
+}
+
+func TestFetchPin(t *testing.T) {
+	dir, first, second := gitRepo(t)
+	opts := func(pin string) Options { return Options{CacheDir: t.TempDir(), Pin: pin} }
+
+	if _, err := Fetch(io.Discard, dir, opts(second)); err != nil {
+		t.Errorf("pin at HEAD: %v", err)
+	}
+	if _, err := Fetch(io.Discard, dir, opts(strings.ToUpper(second[:7]))); err != nil {
+		t.Errorf("abbreviated pin at HEAD: %v", err)
+	}
+
+	tests := []struct {
+		name, pin, err string
+	}{
+		{"pin behind HEAD", first, "expected pinned commit " + first},
+		{"unknown commit", strings.Repeat("0", 40), "not found"},
+		{"too short", second[:6], "at least 7 hex characters"},
+		{"not hex", "release-1", "at least 7 hex characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Fetch(io.Discard, dir, opts(tt.pin))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Fetch with pin %q = %v, want %q", tt.pin, err, tt.err)
+			}
+		})
+	}
+
+	if _, err := Fetch(io.Discard, t.TempDir(), opts(second)); err == nil || !strings.Contains(err.Error(), "not a git checkout") {
+		t.Errorf("pin on a plain directory = %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,17 +16,31 @@ import (
 type phase int
 
 const (
-	phaseSelect  phase = iota
+	phaseSelect phase = iota
+	phasePreflight
 	phaseInstall
 	phaseDone
 )
 
+// Prepare runs on the terminal, outside the TUI, once the modules to install
+// are known. It asks for anything that cannot be asked inside the TUI, such
+// as passphrases and the sudo password.
+type Prepare func(modules []module.Module) error
+
 // Model is the Bubbletea model.
 type Model struct {
-	modules  []module.Module
-	cursor   int
-	selected map[int]bool
-	phase    phase
+	prepare   Prepare
+	modules   []module.Module
+	cursor    int
+	selected  map[int]bool
+	collapsed map[string]bool
+	phase     phase
+
+	// Preflight phase
+	checking bool // preflight checks still running
+	pending  []module.Module
+	reports  []module.PreflightReport
+	skip     map[int]bool
 
 	// Install phase
 	installing int
@@ -34,6 +49,10 @@ type Model struct {
 	logs       []string
 	startTime  time.Time
 	elapsed    time.Duration
+
+	// Done phase
+	checkingSteps bool
+	steps         []module.Step // pending manual steps
 }
 
 type installResult struct {
@@ -50,23 +69,113 @@ type installDoneMsg struct {
 
 type tickMsg time.Time
 
-// New creates a new TUI model.
-func New() Model {
+// preflightMsg carries the preflight reports of the modules to install.
+type preflightMsg struct {
+	modules []module.Module
+	reports []module.PreflightReport
+}
+
+// stepsMsg carries the manual steps that are still pending.
+type stepsMsg []module.Step
+
+// preparedMsg reports that Prepare finished for the modules to install.
+type preparedMsg struct {
+	modules []module.Module
+	err     error
+}
+
+// prepareExec runs a Prepare with the terminal released by bubbletea.
+type prepareExec struct {
+	prepare Prepare
+	modules []module.Module
+}
+
+func (e prepareExec) Run() error          { return e.prepare(e.modules) }
+func (e prepareExec) SetStdin(io.Reader)  {}
+func (e prepareExec) SetStdout(io.Writer) {}
+func (e prepareExec) SetStderr(io.Writer) {}
+
+// row is a line of the selection list: a category header (index -1) or a
+// module within it.
+type row struct {
+	category string
+	index    int
+}
+
+// New creates a new TUI model. prepare, if not nil, runs before the
+// selected modules are installed.
+func New(prepare Prepare) Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = spinnerStyle
 
-	mods := module.All()
+	mods := append([]module.Module(nil), module.All()...)
+	sort.SliceStable(mods, func(i, j int) bool {
+		return categoryIndex(mods[i]) < categoryIndex(mods[j])
+	})
 	selected := make(map[int]bool)
-	for i := range mods {
-		selected[i] = true
+	for i, mod := range mods {
+		selected[i] = module.Supported(mod)
 	}
 
 	return Model{
-		modules:  mods,
-		selected: selected,
-		spinner:  s,
+		prepare:   prepare,
+		modules:   mods,
+		selected:  selected,
+		collapsed: make(map[string]bool),
+		spinner:   s,
+	}
+}
+
+func categoryIndex(m module.Module) int {
+	c := module.Category(m)
+	for i, name := range module.Categories {
+		if name == c {
+			return i
+		}
 	}
+	return len(module.Categories)
+}
+
+// rows returns the visible lines of the selection list. Modules are already
+// sorted by category, so a header is emitted whenever the category changes.
+func (m Model) rows() []row {
+	var rows []row
+	last := ""
+	for i, mod := range m.modules {
+		c := module.Category(mod)
+		if c != last {
+			rows = append(rows, row{category: c, index: -1})
+			last = c
+		}
+		if !m.collapsed[c] {
+			rows = append(rows, row{category: c, index: i})
+		}
+	}
+	return rows
+}
+
+// setCategory selects or deselects every supported module in a category.
+func (m Model) setCategory(category string, on bool) {
+	for i, mod := range m.modules {
+		if module.Category(mod) == category && module.Supported(mod) {
+			m.selected[i] = on
+		}
+	}
+}
+
+// categoryCount returns how many modules in a category are selected, and
+// how many there are.
+func (m Model) categoryCount(category string) (selected, total int) {
+	for i, mod := range m.modules {
+		if module.Category(mod) == category {
+			total++
+			if m.selected[i] {
+				selected++
+			}
+		}
+	}
+	return selected, total
 }
 
 func (m Model) Init() tea.Cmd {
@@ -74,13 +183,27 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(preparedMsg); ok {
+		if msg.err != nil {
+			m.logs = append(m.logs, fmt.Sprintf("Error: %v", msg.err))
+			m.phase = phaseDone
+			return m, nil
+		}
+		return m.runModules(msg.modules)
+	}
 	switch m.phase {
 	case phaseSelect:
 		return m.updateSelect(msg)
+	case phasePreflight:
+		return m.updatePreflight(msg)
 	case phaseInstall:
 		return m.updateInstall(msg)
 	case phaseDone:
-		if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg := msg.(type) {
+		case stepsMsg:
+			m.steps = msg
+			m.checkingSteps = false
+		case tea.KeyMsg:
 			if msg.String() == "q" || msg.String() == "enter" || msg.String() == "ctrl+c" {
 				return m, tea.Quit
 			}
@@ -93,6 +216,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) updateSelect(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		rows := m.rows()
+		cur := rows[m.cursor]
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
@@ -101,21 +226,35 @@ func (m Model) updateSelect(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor--
 			}
 		case "down", "j":
-			if m.cursor < len(m.modules)-1 {
+			if m.cursor < len(rows)-1 {
 				m.cursor++
 			}
 		case " ":
-			m.selected[m.cursor] = !m.selected[m.cursor]
+			if cur.index < 0 {
+				n, total := m.categoryCount(cur.category)
+				m.setCategory(cur.category, n < total)
+			} else if module.Supported(m.modules[cur.index]) {
+				m.selected[cur.index] = !m.selected[cur.index]
+			}
+		case "left", "h":
+			m.collapsed[cur.category] = true
+			for i, r := range m.rows() {
+				if r.index < 0 && r.category == cur.category {
+					m.cursor = i
+				}
+			}
+		case "right", "l":
+			m.collapsed[cur.category] = false
 		case "a":
 			allSelected := true
-			for i := range m.modules {
-				if !m.selected[i] {
+			for i, mod := range m.modules {
+				if module.Supported(mod) && !m.selected[i] {
 					allSelected = false
 					break
 				}
 			}
-			for i := range m.modules {
-				m.selected[i] = !allSelected
+			for i, mod := range m.modules {
+				m.selected[i] = !allSelected && module.Supported(mod)
 			}
 		case "enter":
 			return m.startInstall()
@@ -146,7 +285,8 @@ func (m Model) updateInstall(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if next >= len(m.results) {
 			m.phase = phaseDone
 			m.elapsed = time.Since(m.startTime)
-			return m, nil
+			m.checkingSteps = true
+			return m, checkSteps(m.succeeded())
 		}
 		m.installing = next
 		return m, m.runInstall(next)
@@ -174,8 +314,90 @@ func (m Model) startInstall() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	m.modules = resolved
-	m.results = make([]installResult, len(resolved))
+	// Check requirements before changing anything. Some checks reach out
+	// to the network, so they run in the background.
+	module.SetInstalling(resolved)
+	m.phase = phasePreflight
+	m.checking = true
+	check := func() tea.Msg {
+		return preflightMsg{modules: resolved, reports: module.Preflight(resolved)}
+	}
+	return m, tea.Batch(m.spinner.Tick, check)
+}
+
+func (m Model) updatePreflight(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case spinner.TickMsg:
+		if !m.checking {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	case preflightMsg:
+		m.checking = false
+		if len(msg.reports) == 0 {
+			return m.beginInstall(msg.modules)
+		}
+		m.pending = msg.modules
+		m.reports = msg.reports
+		m.skip = make(map[int]bool)
+		for i, r := range msg.reports {
+			m.skip[i] = r.Failed()
+		}
+		m.cursor = 0
+		return m, nil
+	case tea.KeyMsg:
+		if m.checking {
+			if msg.String() == "q" || msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.reports)-1 {
+				m.cursor++
+			}
+		case " ":
+			m.skip[m.cursor] = !m.skip[m.cursor]
+		case "enter":
+			var skipped []string
+			for i, r := range m.reports {
+				if m.skip[i] {
+					skipped = append(skipped, r.Module.ID())
+				}
+			}
+			mods := module.Exclude(m.pending, skipped)
+			if len(mods) == 0 {
+				return m, tea.Quit
+			}
+			return m.beginInstall(mods)
+		}
+	}
+	return m, nil
+}
+
+// beginInstall runs Prepare for mods on the terminal, then installs them.
+func (m Model) beginInstall(mods []module.Module) (tea.Model, tea.Cmd) {
+	module.SetInstalling(mods)
+	if m.prepare == nil {
+		return m.runModules(mods)
+	}
+	return m, tea.Exec(prepareExec{m.prepare, mods}, func(err error) tea.Msg {
+		return preparedMsg{modules: mods, err: err}
+	})
+}
+
+func (m Model) runModules(mods []module.Module) (tea.Model, tea.Cmd) {
+	m.modules = mods
+	m.results = make([]installResult, len(mods))
 	m.phase = phaseInstall
 	m.startTime = time.Now()
 	m.installing = 0
@@ -188,7 +410,7 @@ func (m Model) runInstall(index int) tea.Cmd {
 	return func() tea.Msg {
 		var buf strings.Builder
 		start := time.Now()
-		err := mod.Install(&buf)
+		err := module.InstallModule(&buf, mod)
 		return installDoneMsg{
 			index:   index,
 			err:     err,
@@ -208,6 +430,8 @@ func (m Model) View() string {
 	switch m.phase {
 	case phaseSelect:
 		return m.viewSelect()
+	case phasePreflight:
+		return m.viewPreflight()
 	case phaseInstall:
 		return m.viewInstall()
 	case phaseDone:
@@ -220,36 +444,95 @@ func (m Model) viewSelect() string {
 	var b strings.Builder
 	b.WriteString(titleStyle.Render(banner))
 	b.WriteString("\n\n")
-	b.WriteString(dimStyle.Render("  Select modules to install (space=toggle, a=all, enter=start, q=quit)"))
-	b.WriteString("\n\n")
+	b.WriteString(dimStyle.Render("  Select modules to install (space=toggle, ←/→=collapse/expand, a=all, enter=start, q=quit)"))
+	b.WriteString("\n")
 
-	for i, mod := range m.modules {
+	for r, row := range m.rows() {
 		cursor := "  "
-		if m.cursor == i {
+		if m.cursor == r {
 			cursor = selectedStyle.Render("▸ ")
 		}
 
+		if row.index < 0 {
+			arrow := "▾"
+			if m.collapsed[row.category] {
+				arrow = "▸"
+			}
+			n, total := m.categoryCount(row.category)
+			header := titleStyle.Render(fmt.Sprintf("%s %s", arrow, module.CategoryName(row.category)))
+			fmt.Fprintf(&b, "\n%s%s %s\n", cursor, header, dimStyle.Render(fmt.Sprintf("(%d/%d)", n, total)))
+			continue
+		}
+
+		i, mod := row.index, m.modules[row.index]
+
 		check := dimStyle.Render("○")
 		if m.selected[i] {
 			check = checkStyle.Render("●")
 		}
 
 		name := mod.Name()
-		if m.cursor == i {
+		if m.cursor == r {
 			name = selectedStyle.Render(name)
 		}
 
 		deps := ""
-		if d := mod.Dependencies(); len(d) > 0 {
+		if hint := module.SupportHint(mod); hint != "" {
+			name = dimStyle.Render(mod.Name())
+			deps = dimStyle.Render(fmt.Sprintf(" (%s)", hint))
+		} else if d := mod.Dependencies(); len(d) > 0 {
 			deps = depHintStyle.Render(fmt.Sprintf(" (requires %s)", strings.Join(d, ", ")))
 		}
 
-		fmt.Fprintf(&b, "%s%s %s%s\n", cursor, check, name, deps)
+		fmt.Fprintf(&b, "%s  %s %s%s\n", cursor, check, name, deps)
 	}
 
 	return b.String()
 }
 
+func (m Model) viewPreflight() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render(banner))
+	b.WriteString("\n\n")
+	if m.checking {
+		fmt.Fprintf(&b, "  %s Running preflight checks...\n", m.spinner.View())
+		return b.String()
+	}
+	b.WriteString(dimStyle.Render("  Preflight found problems (space=skip/keep, enter=install, q=quit)"))
+	b.WriteString("\n\n")
+
+	for i, r := range m.reports {
+		cursor := "  "
+		if m.cursor == i {
+			cursor = selectedStyle.Render("▸ ")
+		}
+		check := checkStyle.Render("●")
+		name := r.Module.Name()
+		if m.skip[i] {
+			check = dimStyle.Render("○")
+			name = dimStyle.Render(name + " (skipped)")
+		} else if m.cursor == i {
+			name = selectedStyle.Render(name)
+		}
+		fmt.Fprintf(&b, "%s%s %s\n", cursor, check, name)
+
+		for _, res := range r.Results {
+			icon := spinnerStyle.Render("!")
+			if res.Severity == module.Fail {
+				icon = crossStyle.Render("✗")
+			}
+			fmt.Fprintf(&b, "      %s %s\n", icon, res.Message)
+			if res.Fix != "" {
+				fmt.Fprintf(&b, "        %s\n", dimStyle.Render("fix: "+res.Fix))
+			}
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render("  Skipping a module also skips the modules that depend on it."))
+	b.WriteString("\n")
+	return b.String()
+}
+
 func (m Model) viewInstall() string {
 	var b strings.Builder
 	b.WriteString(titleStyle.Render(banner))
@@ -307,8 +590,15 @@ func (m Model) viewDone() string {
 	}
 	b.WriteString("\n\n")
 
-	for i, mod := range m.modules {
-		r := m.results[i]
+	// Nothing was installed when resolving or preparing failed; the error
+	// is in the logs.
+	if len(m.results) == 0 {
+		for _, line := range m.logs {
+			fmt.Fprintf(&b, "  %s %s\n", crossStyle.Render("✗"), crossStyle.Render(line))
+		}
+	}
+	for i, r := range m.results {
+		mod := m.modules[i]
 		if r.err != nil {
 			fmt.Fprintf(&b, "  %s %s %s\n", crossStyle.Render("✗"), mod.Name(),
 				crossStyle.Render(r.err.Error()))
@@ -319,7 +609,12 @@ func (m Model) viewDone() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(postInstallInstructions(m.modules))
+	if m.checkingSteps {
+		b.WriteString(dimStyle.Render("  Checking manual steps..."))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(renderSteps(m.steps))
+	}
 	b.WriteString("\n")
 	b.WriteString(dimStyle.Render("  Press q or enter to exit"))
 	b.WriteString("\n")
@@ -339,27 +634,52 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", s)
 }
 
-func postInstallInstructions(modules []module.Module) string {
-	ids := make(map[string]bool)
-	for _, m := range modules {
-		ids[m.ID()] = true
+// succeeded returns the modules that installed without error.
+func (m Model) succeeded() []module.Module {
+	var result []module.Module
+	for i, mod := range m.modules {
+		if m.results[i].err == nil {
+			result = append(result, mod)
+		}
 	}
+	return result
+}
 
-	var b strings.Builder
-	b.WriteString(titleStyle.Render("  Manual Steps:"))
-	b.WriteString("\n\n")
-
-	if ids["ssh"] {
-		b.WriteString("  1. gh auth login\n")
-		b.WriteString("  2. gh ssh-key add ~/.ssh/id_ed25519.pub -t \"Mac\"\n")
+// checkSteps works out the pending manual steps in the background; checking
+// them runs commands and may query GitHub.
+func checkSteps(modules []module.Module) tea.Cmd {
+	return func() tea.Msg {
+		return stepsMsg(pendingSteps(modules))
 	}
-	if ids["fish"] {
-		b.WriteString("  3. Open a new terminal to start using Fish shell\n")
+}
+
+// pendingSteps returns the follow-up steps of the installed modules that are
+// not done yet.
+func pendingSteps(modules []module.Module) []module.Step {
+	var pending []module.Step
+	for _, step := range module.Steps(modules) {
+		if !step.IsDone() {
+			pending = append(pending, step)
+		}
 	}
-	if ids["neovim"] {
-		b.WriteString("  4. Run nvim once to install LazyVim plugins\n")
+	return pending
+}
+
+// renderSteps lists the pending manual steps, numbered in order.
+func renderSteps(pending []module.Step) string {
+	if len(pending) == 0 {
+		return ""
 	}
 
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("  Manual Steps:"))
+	b.WriteString("\n\n")
+	for i, step := range pending {
+		fmt.Fprintf(&b, "  %d. %s: %s\n", i+1, step.Title, step.Action)
+	}
+	b.WriteString("\n")
+	b.WriteString(dimStyle.Render("  Run henrik-os todo to see what is left later"))
+	b.WriteString("\n")
 	return b.String()
 }
 
@@ -378,15 +698,17 @@ func (m Model) SelectedIDs() []string {
 func RunHeadless(modules []module.Module, w io.Writer) error {
 	start := time.Now()
 	var failed []string
+	var installed []module.Module
 
 	for _, mod := range modules {
 		fmt.Fprintf(w, "\n══ %s ══\n", mod.Name())
 		mStart := time.Now()
-		if err := mod.Install(w); err != nil {
+		if err := module.InstallModule(w, mod); err != nil {
 			fmt.Fprintf(w, "  ✗ Failed: %v (%s)\n", err, formatDuration(time.Since(mStart)))
 			failed = append(failed, mod.Name())
 		} else {
 			fmt.Fprintf(w, "  ✓ Done (%s)\n", formatDuration(time.Since(mStart)))
+			installed = append(installed, mod)
 		}
 	}
 
@@ -395,6 +717,6 @@ func RunHeadless(modules []module.Module, w io.Writer) error {
 		fmt.Fprintf(w, "Failed: %s\n", strings.Join(failed, ", "))
 	}
 	fmt.Fprintln(w)
-	fmt.Fprint(w, postInstallInstructions(modules))
+	fmt.Fprint(w, renderSteps(pendingSteps(installed)))
 	return nil
 }